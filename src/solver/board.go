@@ -0,0 +1,392 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// symbols are the characters used for values on a Board - digits, then
+// letters for boards with more than 9 rows.
+const symbols = "123456789ABCDEFGHIJKLMNOP"
+
+// symbol returns the character for a value, or 0 for an empty cell.
+func symbol(value int) byte {
+	if value < 1 || value > len(symbols) {
+		return '0'
+	}
+	return symbols[value-1]
+}
+
+// symbolValue returns the value of a character, ignoring case, or -1 if it
+// isn't a symbol.
+func symbolValue(r rune) int {
+	if r >= 'a' && r <= 'z' {
+		r -= 'a' - 'A'
+	}
+	i := strings.IndexRune(symbols, r)
+	if i < 0 {
+		return -1
+	}
+	return i + 1
+}
+
+// Board is a Sudoku grid of any size up to 25x25, such as 4x4 or 6x6 for
+// children or 16x16 hexadoku. Its boxes are BoxRows cells tall and BoxColumns
+// cells wide, so it has BoxRows*BoxColumns rows, columns and boxes. Values go
+// from 1 to the number of rows and are written as 1 to 9 then A to P, with 0
+// representing an empty cell. Use Grid for the usual 9x9 puzzles.
+//
+// Jigsaw boards have irregular regions in place of boxes. BoxRows and
+// BoxColumns then only give the size of the board.
+type Board struct {
+	BoxRows    int
+	BoxColumns int
+	// Cells holds the values row by row.
+	Cells []int
+	// Regions holds the region of each cell of a jigsaw board, from 0 to
+	// the number of rows less 1. It is nil for boards with boxes. Jigsaw
+	// boards made by NewJigsawBoard, ParseJigsaw and UnmarshalJSON build the
+	// layout of their regions once, so don't change Regions afterwards.
+	Regions []int
+	// Cages holds the cages of a Killer Sudoku. Every cage's values must add
+	// up to its sum without repeating.
+	Cages []Cage
+	// Constraints holds the rules of a variant beyond the rows, columns,
+	// boxes and cages.
+	Constraints []Constraint
+
+	// jigsaw is the layout built for Regions when the board was made
+	jigsaw *layout
+}
+
+// NewBoard returns an empty board with boxes boxRows tall and boxColumns
+// wide.
+func NewBoard(boxRows, boxColumns int) (*Board, error) {
+	if boxRows < 1 || boxColumns < 1 || boxRows*boxColumns > MaxBoardSize {
+		return nil, fmt.Errorf("%dx%d boxes do not fit a board of at most %d rows", boxRows, boxColumns, MaxBoardSize)
+	}
+	size := boxRows * boxColumns
+	return &Board{BoxRows: boxRows, BoxColumns: boxColumns, Cells: make([]int, size*size)}, nil
+}
+
+// NewJigsawBoard returns an empty jigsaw board with the regions returned by
+// ParseRegions.
+func NewJigsawBoard(regions []int) (*Board, error) {
+	size := int(math.Sqrt(float64(len(regions))))
+	if err := checkRegions(regions, size); err != nil {
+		return nil, err
+	}
+	boxRows, boxColumns, err := BoxShape(size)
+	if err != nil {
+		boxRows, boxColumns = 1, size
+	}
+	b, err := NewBoard(boxRows, boxColumns)
+	if err != nil {
+		return nil, err
+	}
+	b.Regions = append([]int(nil), regions...)
+	b.jigsaw = layoutForRegions(b.Regions)
+	return b, nil
+}
+
+// BoardFromGrid returns a 9x9 board holding the grid's values.
+func BoardFromGrid(grid Grid) *Board {
+	return &Board{BoxRows: 3, BoxColumns: 3, Cells: append([]int(nil), grid[:]...)}
+}
+
+// Grid returns the board as a Grid. Returns an error if it isn't 9x9 with
+// 3x3 boxes, or is a jigsaw board or has cages or other constraints.
+func (b *Board) Grid() (Grid, error) {
+	var grid Grid
+	if b.Regions != nil {
+		return grid, fmt.Errorf("a jigsaw board is not a Grid")
+	}
+	if len(b.Cages) > 0 {
+		return grid, fmt.Errorf("a board with cages is not a Grid")
+	}
+	if len(b.Constraints) > 0 {
+		return grid, fmt.Errorf("a board with variant constraints is not a Grid")
+	}
+	if b.BoxRows != 3 || b.BoxColumns != 3 || len(b.Cells) != 81 {
+		return grid, fmt.Errorf("a %dx%d board with %dx%d boxes is not a Grid", b.Size(), b.Size(), b.BoxRows, b.BoxColumns)
+	}
+	copy(grid[:], b.Cells)
+	return grid, nil
+}
+
+// Size returns the number of rows, columns and boxes.
+func (b *Board) Size() int {
+	return b.BoxRows * b.BoxColumns
+}
+
+// Clone produces a copy of the board.
+func (b *Board) Clone() *Board {
+	clone := *b
+	clone.Cells = append([]int(nil), b.Cells...)
+	if b.Regions != nil {
+		clone.Regions = append([]int(nil), b.Regions...)
+	}
+	if b.Cages != nil {
+		clone.Cages = make([]Cage, len(b.Cages))
+		for c, cage := range b.Cages {
+			clone.Cages[c] = Cage{Sum: cage.Sum, Cells: append([]int(nil), cage.Cells...)}
+		}
+	}
+	if b.Constraints != nil {
+		clone.Constraints = append([]Constraint(nil), b.Constraints...)
+	}
+	return &clone
+}
+
+func (b *Board) layout() *layout {
+	if b.Regions != nil {
+		if b.jigsaw != nil {
+			return b.jigsaw
+		}
+		// a board put together by hand has no layout of its own
+		return layoutForRegions(b.Regions)
+	}
+	return layoutFor(b.BoxRows, b.BoxColumns)
+}
+
+func (b *Board) String() string {
+	s := make([]byte, len(b.Cells))
+	for i, value := range b.Cells {
+		s[i] = symbol(value)
+	}
+	return string(s)
+}
+
+// boardJSON is the JSON form of a Board, which describes variants that can't
+// be written as a plain puzzle.
+type boardJSON struct {
+	// Puzzle is in a form accepted by ParseBoard, or empty for a board with
+	// no values yet.
+	Puzzle string `json:"puzzle"`
+	// Box is the box shape, such as 2x3. It is worked out from the puzzle if
+	// empty, and defaults to 3x3 if the puzzle is empty too.
+	Box string `json:"box,omitempty"`
+	// Layout is a jigsaw layout in the form accepted by ParseRegions.
+	Layout string `json:"layout,omitempty"`
+	Cages  []Cage `json:"cages,omitempty"`
+	// Variants names built-in variants, as accepted by ParseVariants.
+	Variants     []string            `json:"variants,omitempty"`
+	Kropki       *KropkiConstraint   `json:"kropki,omitempty"`
+	Thermometers [][]int             `json:"thermometers,omitempty"`
+	Arrows       []Arrow             `json:"arrows,omitempty"`
+	Sandwiches   *SandwichConstraint `json:"sandwiches,omitempty"`
+}
+
+// MarshalJSON encodes the board as an object with the puzzle, the box shape
+// or jigsaw layout, any cages, the names of any built-in variants, and any
+// Kropki dots, thermometers, arrows and sandwich clues. Other constraints are
+// left out.
+func (b *Board) MarshalJSON() ([]byte, error) {
+	j := boardJSON{Puzzle: b.String(), Cages: b.Cages}
+	for _, c := range b.Constraints {
+		if name := VariantName(c); name != "" {
+			j.Variants = append(j.Variants, name)
+		}
+		switch c := c.(type) {
+		case KropkiConstraint:
+			j.Kropki = &c
+		case ThermometerConstraint:
+			j.Thermometers = append(j.Thermometers, c.Thermometers...)
+		case ArrowConstraint:
+			j.Arrows = append(j.Arrows, c.Arrows...)
+		case SandwichConstraint:
+			j.Sandwiches = &c
+		}
+	}
+	if b.Regions != nil {
+		j.Layout = RegionsString(b.Regions)
+	} else {
+		j.Box = fmt.Sprintf("%dx%d", b.BoxRows, b.BoxColumns)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a board encoded by MarshalJSON. The puzzle may be
+// left out, for puzzles such as Killer Sudoku which can start with no values,
+// and the box shape may be left out as for ParseBoard.
+func (b *Board) UnmarshalJSON(data []byte) error {
+	var j boardJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	var board *Board
+	var err error
+	switch {
+	case j.Layout != "" && j.Puzzle != "":
+		board, err = ParseJigsaw(j.Puzzle, j.Layout)
+	case j.Layout != "":
+		var regions []int
+		if regions, err = ParseRegions(j.Layout); err != nil {
+			return fmt.Errorf("layout: %v", err)
+		}
+		board, err = NewJigsawBoard(regions)
+	default:
+		boxRows, boxColumns := 0, 0
+		if j.Box != "" {
+			if boxRows, boxColumns, err = ParseBoxShape(j.Box); err != nil {
+				return err
+			}
+		}
+		if j.Puzzle == "" {
+			if j.Box == "" {
+				boxRows, boxColumns = 3, 3
+			}
+			board, err = NewBoard(boxRows, boxColumns)
+		} else {
+			board, err = ParseBoard(j.Puzzle, boxRows, boxColumns)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	board.Cages = j.Cages
+	if err := checkCages(board.Cages, board.Size()); err != nil {
+		return err
+	}
+	if board.Constraints, err = ParseVariants(strings.Join(j.Variants, ",")); err != nil {
+		return err
+	}
+	var shaped []shapedConstraint
+	if j.Kropki != nil {
+		shaped = append(shaped, *j.Kropki)
+	}
+	if j.Thermometers != nil {
+		shaped = append(shaped, ThermometerConstraint{Thermometers: j.Thermometers})
+	}
+	if j.Arrows != nil {
+		shaped = append(shaped, ArrowConstraint{Arrows: j.Arrows})
+	}
+	if j.Sandwiches != nil {
+		shaped = append(shaped, *j.Sandwiches)
+	}
+	for _, c := range shaped {
+		if err := c.checkShape(board.Size()); err != nil {
+			return err
+		}
+		board.Constraints = append(board.Constraints, c)
+	}
+	*b = *board
+	return nil
+}
+
+// Print prints the board to the writer, with lines between the boxes. Jigsaw
+// boards are printed without lines, since their regions don't line up.
+func (b *Board) Print(w io.Writer) {
+	size := b.Size()
+	if b.Regions != nil {
+		for i, value := range b.Cells {
+			if value == 0 {
+				fmt.Fprint(w, ".")
+			} else {
+				fmt.Fprintf(w, "%c", symbol(value))
+			}
+			if i%size == size-1 {
+				fmt.Fprintln(w)
+			}
+		}
+		return
+	}
+	divider := strings.Repeat(strings.Repeat("-", b.BoxColumns)+"+", b.BoxRows)
+	divider = divider[:len(divider)-1]
+	for i, value := range b.Cells {
+		row, column := i/size, i%size
+		if column == 0 && row > 0 && row%b.BoxRows == 0 {
+			fmt.Fprintln(w, divider)
+		}
+		if value == 0 {
+			fmt.Fprint(w, ".")
+		} else {
+			fmt.Fprintf(w, "%c", symbol(value))
+		}
+		if column == size-1 {
+			fmt.Fprintln(w)
+		} else if column%b.BoxColumns == b.BoxColumns-1 {
+			fmt.Fprint(w, "|")
+		}
+	}
+}
+
+// Validate checks that the board's shape, regions and cages are right, that
+// every value is from 0 to the number of rows and that the values follow
+// every constraint - no value appearing twice in a row, column, box, region
+// or cage, every cage still able to add up to its sum, and the rules of any
+// variant. Returns a *ValidationError listing every problem with the values,
+// or nil.
+func (b *Board) Validate() error {
+	size := b.Size()
+	if b.BoxRows < 1 || b.BoxColumns < 1 || size > MaxBoardSize {
+		return fmt.Errorf("%dx%d boxes do not fit a board of at most %d rows", b.BoxRows, b.BoxColumns, MaxBoardSize)
+	}
+	if len(b.Cells) != size*size {
+		return fmt.Errorf("expected %d cells - got %d instead", size*size, len(b.Cells))
+	}
+	if b.Regions != nil {
+		if err := checkRegions(b.Regions, size); err != nil {
+			return err
+		}
+	}
+	if err := checkCages(b.Cages, size); err != nil {
+		return err
+	}
+	e := &ValidationError{size: size, cages: b.Cages}
+	for i, value := range b.Cells {
+		if value < 0 || value > size {
+			e.OutOfRange = append(e.OutOfRange, i)
+		}
+	}
+	for _, c := range b.constraints() {
+		c.Check(b, e)
+	}
+	if e.empty() {
+		return nil
+	}
+	return e
+}
+
+// Solve fills in the board, sending an UpdateEvent to ch for every change
+// unless ch is nil. Returns the error from Validate if the board is invalid,
+// ErrUnsolvable if it has no solution, or ErrCancelled or ErrTimedOut if ctx
+// is done before a solution is found. The board is left as it was if the
+// search does not succeed.
+func (b *Board) Solve(ctx context.Context, ch chan UpdateEvent) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	s := newBoardSearch(b, ch)
+	s.canceller = newCanceller(ctx)
+	if s.run(func() bool { return false }) {
+		return nil
+	}
+	return solveError(ctx, s.stopped)
+}
+
+// CountSolutions returns the number of solutions to the board, stopping once
+// limit solutions have been found. A limit of 0 or less counts every
+// solution. Counting the solutions to a large board with few givens can take
+// a very long time, so it gives up once ctx is done, returning the solutions
+// found so far with ErrCancelled or ErrTimedOut.
+func (b *Board) CountSolutions(ctx context.Context, limit int) (int, error) {
+	if err := b.Validate(); err != nil {
+		return 0, err
+	}
+	count := 0
+	s := newBoardSearch(b.Clone(), nil)
+	s.canceller = newCanceller(ctx)
+	s.run(func() bool {
+		count++
+		return limit <= 0 || count < limit
+	})
+	if s.stopped {
+		return count, solveError(ctx, true)
+	}
+	return count, nil
+}
@@ -0,0 +1,201 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// patternBoard returns a solved board with some cells emptied at random.
+func patternBoard(boxRows, boxColumns int, empty float64) *Board {
+	b, _ := NewBoard(boxRows, boxColumns)
+	size := b.Size()
+	r := rand.New(rand.NewSource(int64(size)))
+	for i := range b.Cells {
+		row, column := i/size, i%size
+		if r.Float64() >= empty {
+			b.Cells[i] = ((row%boxRows)*boxColumns+row/boxRows+column)%size + 1
+		}
+	}
+	return b
+}
+
+func TestBoardSolve(t *testing.T) {
+	shapes := [][2]int{{2, 2}, {2, 3}, {3, 3}, {2, 4}, {3, 4}, {4, 4}, {5, 5}}
+	for _, shape := range shapes {
+		b := patternBoard(shape[0], shape[1], 0.5)
+		if err := b.Validate(); err != nil {
+			t.Fatalf("%dx%d: unexpected error %v", shape[0], shape[1], err)
+		}
+		ch := make(chan UpdateEvent, 100)
+		done := make(chan int)
+		go func() {
+			n := 0
+			for range ch {
+				n++
+			}
+			done <- n
+		}()
+		err := b.Solve(context.Background(), ch)
+		close(ch)
+		if err != nil {
+			t.Errorf("%dx%d: unexpected error %v", shape[0], shape[1], err)
+			continue
+		}
+		if <-done == 0 {
+			t.Errorf("%dx%d: expected update events", shape[0], shape[1])
+		}
+		if err := b.Validate(); err != nil {
+			t.Errorf("%dx%d: solution is invalid: %v", shape[0], shape[1], err)
+		}
+		for i, value := range b.Cells {
+			if value == 0 {
+				t.Errorf("%dx%d: %s is empty after solving", shape[0], shape[1], cellNameOn(i, b.Size()))
+				break
+			}
+		}
+	}
+}
+
+func TestBoardMatchesGrid(t *testing.T) {
+	grid, _ := NewGridFromString(testPuzzle)
+	b := BoardFromGrid(grid)
+	if b.String() != testPuzzle {
+		t.Errorf("expected %s - got %s instead", testPuzzle, b)
+	}
+	if err := b.Solve(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	grid.Solve(nil)
+	solved, err := b.Grid()
+	if err != nil || solved != grid {
+		t.Errorf("expected %s - got %s, %v instead", grid, solved, err)
+	}
+	if count, err := BoardFromGrid(Grid{}).CountSolutions(context.Background(), 3); err != nil || count != 3 {
+		t.Errorf("expected the empty board to have at least 3 solutions - got %d, %v", count, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := BoardFromGrid(Grid{}).CountSolutions(ctx, 0); err != ErrCancelled {
+		t.Errorf("expected %v counting every solution to the empty board - got %v", ErrCancelled, err)
+	}
+	b, _ = NewBoard(2, 3)
+	if _, err := b.Grid(); err == nil {
+		t.Error("expected an error converting a 6x6 board to a Grid")
+	}
+}
+
+func TestBoardValidate(t *testing.T) {
+	b, _ := ParseBoard("1.1. .... .... ....", 0, 0)
+	b.Cells[15] = 5
+	err := b.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError - got %v", err)
+	}
+	if len(verr.OutOfRange) != 1 || verr.OutOfRange[0] != 15 {
+		t.Errorf("expected r4c4 to be out of range - got %v", verr.OutOfRange)
+	}
+	if len(verr.Conflicts) != 1 || !strings.Contains(err.Error(), "1 appears twice in row 1, at r1c1 and r1c3") {
+		t.Errorf("unexpected conflicts %v", err)
+	}
+	b.Cells[15] = 0
+	if err := b.Solve(context.Background(), nil); err == nil {
+		t.Error("expected an error solving an invalid board")
+	}
+
+	b, _ = NewBoard(4, 4)
+	b.Cells[0], b.Cells[17] = 16, 16
+	if err := b.Validate(); err == nil || !strings.Contains(err.Error(), "G appears twice in box 1, at r1c1 and r2c2") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseBoard(t *testing.T) {
+	tables := []struct {
+		input                 string
+		boxRows, boxColumns   int
+		expected              string
+		expectedRows, expCols int
+	}{
+		{"12.. 34.. .... ....", 0, 0, "1200340000000000", 2, 2},
+		{"1..2..\n......\n......\n......\n......\n.....6", 0, 0, "100200000000000000000000000000000006", 2, 3},
+		{"1..2..\n......\n......\n......\n......\n.....6", 3, 2, "100200000000000000000000000000000006", 3, 2},
+		{"AbC" + strings.Repeat(".", 141), 3, 4, "ABC" + strings.Repeat("0", 141), 3, 4},
+		{"G" + strings.Repeat("0", 255), 0, 0, "G" + strings.Repeat("0", 255), 4, 4},
+		{testPuzzle, 0, 0, testPuzzle, 3, 3},
+	}
+	for _, table := range tables {
+		b, err := ParseBoard(table.input, table.boxRows, table.boxColumns)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", table.input, err)
+			continue
+		}
+		if b.String() != table.expected || b.BoxRows != table.expectedRows || b.BoxColumns != table.expCols {
+			t.Errorf("%q: expected %s with %dx%d boxes - got %s with %dx%d boxes instead", table.input, table.expected, table.expectedRows, table.expCols, b, b.BoxRows, b.BoxColumns)
+		}
+	}
+
+	errors := []struct {
+		input               string
+		boxRows, boxColumns int
+	}{
+		{"", 0, 0},
+		{"12345", 0, 0},
+		// 7 has no box shape
+		{strings.Repeat(".", 49), 0, 0},
+		// 5 is too big for 4x4
+		{"5" + strings.Repeat(".", 15), 0, 0},
+		{strings.Repeat(".", 16), 2, 3},
+	}
+	for _, table := range errors {
+		if _, err := ParseBoard(table.input, table.boxRows, table.boxColumns); err == nil {
+			t.Errorf("%q: expected an error", table.input)
+		}
+	}
+}
+
+func TestBoardPrint(t *testing.T) {
+	b, _ := ParseBoard("1......2......3......4......5......6", 2, 3)
+	var buf bytes.Buffer
+	b.Print(&buf)
+	expected := "1..|...\n.2.|...\n---+---\n..3|...\n...|4..\n---+---\n...|.5.\n...|..6\n"
+	if buf.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+	reparsed, err := ParseBoard(buf.String(), 2, 3)
+	if err != nil || reparsed.String() != b.String() {
+		t.Errorf("could not parse printed board: %v", err)
+	}
+}
+
+func TestBoxShapes(t *testing.T) {
+	shapes := map[int][2]int{4: {2, 2}, 6: {2, 3}, 8: {2, 4}, 9: {3, 3}, 12: {3, 4}, 16: {4, 4}, 25: {5, 5}}
+	for size, shape := range shapes {
+		r, c, err := BoxShape(size)
+		if err != nil || r != shape[0] || c != shape[1] {
+			t.Errorf("expected %dx%d boxes for size %d - got %dx%d, %v instead", shape[0], shape[1], size, r, c, err)
+		}
+	}
+	if _, _, err := BoxShape(7); err == nil {
+		t.Error("expected an error for size 7")
+	}
+	if r, c, err := ParseBoxShape("2x3"); err != nil || r != 2 || c != 3 {
+		t.Errorf("expected 2x3 - got %dx%d, %v", r, c, err)
+	}
+	for _, s := range []string{"2", "axb", "6x6", "0x3"} {
+		if _, _, err := ParseBoxShape(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+func BenchmarkBoardSolve16(b *testing.B) {
+	puzzle := patternBoard(4, 4, 0.5)
+	for i := 0; i < b.N; i++ {
+		board := puzzle.Clone()
+		board.Solve(context.Background(), nil)
+	}
+}
@@ -0,0 +1,226 @@
+package solver
+
+import "math/bits"
+
+// boardSearch holds the state of a backtracking search over a Board.
+type boardSearch struct {
+	canceller
+
+	board *Board
+	size  int
+	ch    chan UpdateEvent
+	// units holds the groups of cells which must hold different values, from
+	// the rows, columns, boxes and any other group constraints, and unitsOf
+	// lists the units of each cell
+	units   [][]int
+	unitsOf [][]int
+	// used holds the values in each unit, with value v in bit v-1
+	used []uint32
+	all  uint32
+	// others holds the constraints which aren't groups, which are asked for
+	// the values they forbid
+	others []Constraint
+	// narrowers holds the others which can rule out more candidates once
+	// they know them all
+	narrowers []narrowingConstraint
+
+	// the board before the search started, restored if the search fails
+	start []int
+	// the candidates for each cell, at each depth of the search
+	scratch [][]uint32
+}
+
+func newBoardSearch(b *Board, ch chan UpdateEvent) *boardSearch {
+	size := b.Size()
+	s := &boardSearch{
+		board:   b,
+		size:    size,
+		ch:      ch,
+		unitsOf: make([][]int, len(b.Cells)),
+		all:     1<<uint(size) - 1,
+		start:   append([]int(nil), b.Cells...),
+	}
+	for _, c := range b.constraints() {
+		g, ok := c.(groupConstraint)
+		if !ok {
+			if p, ok := c.(preparedConstraint); ok {
+				c = p.prepare(b)
+			}
+			s.others = append(s.others, c)
+			if n, ok := c.(narrowingConstraint); ok {
+				s.narrowers = append(s.narrowers, n)
+			}
+			continue
+		}
+		for _, cells := range g.groups(b) {
+			for _, i := range cells {
+				s.unitsOf[i] = append(s.unitsOf[i], len(s.units))
+			}
+			s.units = append(s.units, cells)
+		}
+	}
+	s.used = make([]uint32, len(s.units))
+	for i, value := range b.Cells {
+		if value != 0 {
+			s.toggle(i, value)
+		}
+	}
+	return s
+}
+
+// toggle adds value to, or removes it from, the units of cell i.
+func (s *boardSearch) toggle(i, value int) {
+	bit := uint32(1) << uint(value-1)
+	for _, u := range s.unitsOf[i] {
+		s.used[u] ^= bit
+	}
+}
+
+func (s *boardSearch) candidates(i int) uint32 {
+	var used uint32
+	for _, u := range s.unitsOf[i] {
+		used |= s.used[u]
+	}
+	for _, c := range s.others {
+		used |= c.Forbidden(s.board, i)
+	}
+	return s.all &^ used
+}
+
+func (s *boardSearch) set(i, value int) {
+	s.board.Cells[i] = value
+	s.toggle(i, value)
+	if s.ch != nil {
+		s.send(s.ch, UpdateEvent{Index: i, Value: value})
+	}
+}
+
+func (s *boardSearch) clear(i int) {
+	s.toggle(i, s.board.Cells[i])
+	s.board.Cells[i] = 0
+	if s.ch != nil {
+		s.send(s.ch, UpdateEvent{Index: i, Value: 0})
+	}
+}
+
+// run searches for solutions, calling found with the board filled in for
+// each solution. The search carries on looking for more solutions while found
+// returns true. Returns true if the search stopped at a solution, leaving it
+// in the board, or false if the search ran out of solutions or was cancelled,
+// leaving the board as it was at the start.
+func (s *boardSearch) run(found func() bool) bool {
+	if s.search(found, 0) {
+		return true
+	}
+	copy(s.board.Cells, s.start)
+	return false
+}
+
+// search fills in the board depth cells deep. It branches on the empty cell
+// with the fewest candidates, unless a value has a single place left in a
+// unit with as many cells as a row - a hidden single - which it fills in
+// first. Branching on other values with few places turns out to lead the
+// search astray on variants such as anti-knight.
+func (s *boardSearch) search(found func() bool, depth int) bool {
+	if s.stop() {
+		return false
+	}
+	if depth == len(s.scratch) {
+		s.scratch = append(s.scratch, make([]uint32, len(s.board.Cells)))
+	}
+	candidates := s.scratch[depth]
+
+	for i, value := range s.board.Cells {
+		candidates[i] = 0
+		if value != 0 {
+			continue
+		}
+		candidates[i] = s.candidates(i)
+		if candidates[i] == 0 {
+			return false
+		}
+	}
+	for _, n := range s.narrowers {
+		if !n.narrow(s.board, candidates) {
+			return false
+		}
+	}
+	best, bestCount := -1, 0
+	for i, value := range s.board.Cells {
+		if value != 0 {
+			continue
+		}
+		count := bits.OnesCount32(candidates[i])
+		if best == -1 || count < bestCount {
+			best, bestCount = i, count
+		}
+	}
+	if best == -1 {
+		return !found()
+	}
+
+	bestUnit, bestValue := -1, 0
+	if bestCount > 1 {
+		var places [MaxBoardSize + 1]int
+		for u, cells := range s.units {
+			if len(cells) != s.size {
+				continue
+			}
+			for v := 1; v <= s.size; v++ {
+				places[v] = 0
+			}
+			for _, i := range cells {
+				for m := candidates[i]; m != 0; m &= m - 1 {
+					places[bits.TrailingZeros32(m)+1]++
+				}
+			}
+			for v := 1; v <= s.size; v++ {
+				if s.used[u]&(1<<uint(v-1)) != 0 {
+					continue
+				}
+				if places[v] == 0 {
+					return false
+				}
+				if places[v] == 1 {
+					bestUnit, bestValue = u, v
+				}
+			}
+		}
+	}
+
+	if bestUnit == -1 {
+		for m := candidates[best]; m != 0; m &= m - 1 {
+			if s.try(best, bits.TrailingZeros32(m)+1, found, depth) {
+				return true
+			}
+			if s.stopped {
+				return false
+			}
+		}
+		return false
+	}
+	bit := uint32(1) << uint(bestValue-1)
+	for _, i := range s.units[bestUnit] {
+		if candidates[i]&bit == 0 {
+			continue
+		}
+		if s.try(i, bestValue, found, depth) {
+			return true
+		}
+		if s.stopped {
+			return false
+		}
+	}
+	return false
+}
+
+// try puts value in cell i and searches on from there, clearing the cell
+// again if that doesn't lead to a solution.
+func (s *boardSearch) try(i, value int, found func() bool, depth int) bool {
+	s.set(i, value)
+	if s.search(found, depth+1) {
+		return true
+	}
+	s.clear(i)
+	return false
+}
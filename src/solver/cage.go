@@ -0,0 +1,246 @@
+package solver
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Cage is a Killer Sudoku cage - a group of cells whose values add up to
+// Sum, with no value repeated.
+type Cage struct {
+	Sum int `json:"sum"`
+	// Cells holds the indexes of the cells in the cage.
+	Cells []int `json:"cells"`
+}
+
+// cageCombinations returns every set of count different values from 1 to
+// size which add up to sum, with value v in bit v-1.
+func cageCombinations(size, count, sum int) []uint32 {
+	var combinations []uint32
+	var add func(from, count, sum int, set uint32)
+	add = func(from, count, sum int, set uint32) {
+		if count == 0 {
+			if sum == 0 {
+				combinations = append(combinations, set)
+			}
+			return
+		}
+		for v := from; v <= size && v <= sum; v++ {
+			add(v+1, count-1, sum-v, set|1<<uint(v-1))
+		}
+	}
+	add(1, count, sum, 0)
+	return combinations
+}
+
+// cageAllows returns the values which could go in the empty cells of a cage
+// already holding the values in used, given the cage's combinations. Returns
+// 0 if no combination holds every used value.
+func cageAllows(combinations []uint32, used uint32) uint32 {
+	var allowed uint32
+	for _, set := range combinations {
+		if set&used == used {
+			allowed |= set
+		}
+	}
+	return allowed &^ used
+}
+
+// checkCages returns an error unless every cage on a board with size rows
+// has from 1 to size cells, none of which is in another cage, and a sum
+// which its cells could add up to.
+func checkCages(cages []Cage, size int) error {
+	caged := make(map[int]int)
+	for c, cage := range cages {
+		if len(cage.Cells) == 0 || len(cage.Cells) > size {
+			return fmt.Errorf("cage %d has %d cells - expected 1 to %d", c+1, len(cage.Cells), size)
+		}
+		for _, i := range cage.Cells {
+			if i < 0 || i >= size*size {
+				return fmt.Errorf("cage %d has cell %d, which is not on the board", c+1, i)
+			}
+			if other, ok := caged[i]; ok {
+				if other == c {
+					return fmt.Errorf("cage %d has %s twice", c+1, cellNameOn(i, size))
+				}
+				return fmt.Errorf("%s is in cages %d and %d", cellNameOn(i, size), other+1, c+1)
+			}
+			caged[i] = c
+		}
+		if len(cageCombinations(size, len(cage.Cells), cage.Sum)) == 0 {
+			return fmt.Errorf("%d different values cannot add up to %d in cage %d", len(cage.Cells), cage.Sum, c+1)
+		}
+	}
+	return nil
+}
+
+// impliedCages applies the 45 rule to each row, column and box, returning
+// the cages it implies. Every unit holds each value once, so adds up to 45
+// on a 9x9 board. The cells of a unit which aren't in a cage lying wholly
+// inside it - the innies - therefore add up to 45 less those cages, and
+// being in one unit they can't repeat a value. Likewise if the cages which
+// touch a unit cover it and stick out by a single cell - an outie - that
+// cell holds their total less 45.
+func impliedCages(l *layout, cages []Cage) []Cage {
+	cageOf := make([]int, l.size*l.size)
+	for i := range cageOf {
+		cageOf[i] = -1
+	}
+	for c, cage := range cages {
+		for _, i := range cage.Cells {
+			cageOf[i] = c
+		}
+	}
+	total := l.size * (l.size + 1) / 2
+
+	var implied []Cage
+	for _, cells := range l.units {
+		inUnit := make(map[int]bool, len(cells))
+		for _, i := range cells {
+			inUnit[i] = true
+		}
+		// the cages which touch the unit, and whether each lies inside it
+		touching := make(map[int]bool)
+		covered := true
+		for _, i := range cells {
+			c := cageOf[i]
+			if c == -1 {
+				covered = false
+				continue
+			}
+			if _, ok := touching[c]; ok {
+				continue
+			}
+			inside := true
+			for _, j := range cages[c].Cells {
+				if !inUnit[j] {
+					inside = false
+				}
+			}
+			touching[c] = inside
+		}
+
+		innies := Cage{Sum: total}
+		for _, i := range cells {
+			if c := cageOf[i]; c == -1 || !touching[c] {
+				innies.Cells = append(innies.Cells, i)
+			}
+		}
+		for c, inside := range touching {
+			if inside {
+				innies.Sum -= cages[c].Sum
+			}
+		}
+		if len(innies.Cells) > 0 && len(innies.Cells) < l.size {
+			implied = append(implied, innies)
+		}
+
+		if !covered {
+			continue
+		}
+		outie := Cage{Sum: -total}
+		for c, inside := range touching {
+			outie.Sum += cages[c].Sum
+			if inside {
+				continue
+			}
+			for _, j := range cages[c].Cells {
+				if !inUnit[j] {
+					outie.Cells = append(outie.Cells, j)
+				}
+			}
+		}
+		if len(outie.Cells) == 1 {
+			implied = append(implied, outie)
+		}
+	}
+	return implied
+}
+
+// cageConstraint is the rule that the values in each cage add up to its sum
+// without repeating. It also holds the cages implied by the 45 rule, which
+// help to rule out values but aren't checked.
+type cageConstraint struct {
+	// cages holds the board's own cages, then the implied ones
+	cages        []Cage
+	given        int
+	combinations [][]uint32
+	cagesOf      [][]int
+}
+
+func newCageConstraint(l *layout, cages []Cage) *cageConstraint {
+	c := &cageConstraint{
+		cages:   append(append([]Cage(nil), cages...), impliedCages(l, cages)...),
+		given:   len(cages),
+		cagesOf: make([][]int, l.size*l.size),
+	}
+	c.combinations = make([][]uint32, len(c.cages))
+	for k, cage := range c.cages {
+		c.combinations[k] = cageCombinations(l.size, len(cage.Cells), cage.Sum)
+		for _, i := range cage.Cells {
+			c.cagesOf[i] = append(c.cagesOf[i], k)
+		}
+	}
+	return c
+}
+
+// Forbidden returns the values which are already in a cage holding cell i, or
+// which can't help to make up its sum.
+func (c *cageConstraint) Forbidden(b *Board, i int) uint32 {
+	all := uint32(1)<<uint(b.Size()) - 1
+	allowed := all
+	for _, k := range c.cagesOf[i] {
+		used, _ := cageValues(b.Cells, c.cages[k])
+		allowed &= cageAllows(c.combinations[k], used)
+	}
+	return all &^ allowed
+}
+
+// Check adds a Conflict to e for every pair of cells in a cage holding the
+// same value, and lists the cages whose values can no longer add up to their
+// sum.
+func (c *cageConstraint) Check(b *Board, e *ValidationError) {
+	cages := c.cages[:c.given]
+	groups := make([][]int, len(cages))
+	for k, cage := range cages {
+		groups[k] = cage.Cells
+	}
+	checkGroups(b, "cage", groups, e)
+	for k, cage := range cages {
+		if used, repeated := cageValues(b.Cells, cage); !repeated && !cageCanAddUp(c.combinations[k], len(cage.Cells), used) {
+			e.BrokenCages = append(e.BrokenCages, k)
+		}
+	}
+}
+
+// cageValues returns the values in a cage, with value v in bit v-1, and
+// whether any value is repeated.
+func cageValues(cells []int, cage Cage) (uint32, bool) {
+	var used uint32
+	repeated := false
+	for _, i := range cage.Cells {
+		if value := cells[i]; value > 0 && value <= MaxBoardSize {
+			bit := uint32(1) << uint(value-1)
+			if used&bit != 0 {
+				repeated = true
+			}
+			used |= bit
+		}
+	}
+	return used, repeated
+}
+
+// cageCanAddUp reports whether the empty cells of a cage of count cells could
+// be filled in to make its sum, given its combinations and the values already
+// in it.
+func cageCanAddUp(combinations []uint32, count int, used uint32) bool {
+	if bits.OnesCount32(used) == count {
+		for _, set := range combinations {
+			if set == used {
+				return true
+			}
+		}
+		return false
+	}
+	return cageAllows(combinations, used) != 0
+}
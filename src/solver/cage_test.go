@@ -0,0 +1,138 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	killerPuzzle   = `{"cages":[{"sum":8,"cells":[15,14]},{"sum":10,"cells":[30,21]},{"sum":10,"cells":[57,56,66]},{"sum":17,"cells":[20,19]},{"sum":24,"cells":[51,50,52,59]},{"sum":18,"cells":[0,9,10]},{"sum":8,"cells":[71,70]},{"sum":3,"cells":[23,32]},{"sum":22,"cells":[76,75,74,65]},{"sum":15,"cells":[40,39,49]},{"sum":18,"cells":[48,47,38]},{"sum":3,"cells":[46,37]},{"sum":21,"cells":[16,25,34,43]},{"sum":20,"cells":[7,6,8,17]},{"sum":11,"cells":[53,44,62]},{"sum":7,"cells":[1,2]},{"sum":18,"cells":[28,27,36,18]},{"sum":10,"cells":[22,31]},{"sum":15,"cells":[55,54]},{"sum":22,"cells":[5,4,3,12]},{"sum":15,"cells":[77,68]},{"sum":2,"cells":[11]},{"sum":10,"cells":[26,35]},{"sum":17,"cells":[60,61,69,78]},{"sum":14,"cells":[64,63,73]},{"sum":9,"cells":[24,33]},{"sum":16,"cells":[79,80]},{"sum":9,"cells":[13]},{"sum":4,"cells":[58,67]},{"sum":3,"cells":[72]},{"sum":10,"cells":[42,41]},{"sum":9,"cells":[29]},{"sum":7,"cells":[45]}]}`
+	killerSolution = "534678912672195348198342567859761423426853791713924856961537284287419635345286179"
+)
+
+func TestKillerSolve(t *testing.T) {
+	var b Board
+	if err := json.Unmarshal([]byte(killerPuzzle), &b); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if b.Size() != 9 || len(b.Cages) != 33 {
+		t.Fatalf("expected a 9x9 board with 33 cages - got %dx%d with %d", b.Size(), b.Size(), len(b.Cages))
+	}
+	if _, err := b.Grid(); err == nil {
+		t.Error("expected an error converting a board with cages to a Grid")
+	}
+	if count, err := b.CountSolutions(context.Background(), 0); err != nil || count != 1 {
+		t.Errorf("expected a unique solution - got %d, %v", count, err)
+	}
+	if err := b.Solve(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if b.String() != killerSolution {
+		t.Errorf("expected %s - got %s instead", killerSolution, b.String())
+	}
+
+	data, err := json.Marshal(&b)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var decoded Board
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, &b) {
+		t.Errorf("%s did not decode to the board it came from", data)
+	}
+}
+
+func TestCageCombinations(t *testing.T) {
+	tests := []struct {
+		count, sum int
+		expected   []uint32
+	}{
+		{1, 5, []uint32{0x10}},
+		{2, 3, []uint32{0x3}},
+		{2, 17, []uint32{0x180}},
+		{3, 24, []uint32{0x1c0}},
+		{2, 6, []uint32{0x11, 0xa}},
+		{2, 2, nil},
+		{9, 45, []uint32{0x1ff}},
+	}
+	for _, test := range tests {
+		if actual := cageCombinations(9, test.count, test.sum); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%d cells adding up to %d: expected %x - got %x instead", test.count, test.sum, test.expected, actual)
+		}
+	}
+}
+
+func TestImpliedCages(t *testing.T) {
+	cages := []Cage{
+		{Sum: 3, Cells: []int{0, 1}},
+		{Sum: 15, Cells: []int{2, 3, 4}},
+		{Sum: 29, Cells: []int{5, 6, 7, 8, 17}},
+	}
+	implied := impliedCages(layoutFor(3, 3), cages)
+	expected := []Cage{
+		// the innies of the top row
+		{Sum: 27, Cells: []int{5, 6, 7, 8}},
+		// the outie of the top row
+		{Sum: 2, Cells: []int{17}},
+	}
+	for _, cage := range expected {
+		found := false
+		for _, i := range implied {
+			if reflect.DeepEqual(i, cage) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %v in %v", cage, implied)
+		}
+	}
+}
+
+func TestKillerValidate(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	b.Cages = []Cage{{Sum: 10, Cells: []int{0, 1, 2}}, {Sum: 4, Cells: []int{9, 10}}}
+	if err := b.Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	b.Cells[0], b.Cells[2] = 4, 4
+	b.Cells[9] = 2
+	err := b.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError - got %v", err)
+	}
+	// the first cage repeats a 4, so its sum isn't checked
+	if len(verr.Conflicts) != 3 || verr.Conflicts[2].Unit != "cage" {
+		t.Errorf("unexpected conflicts %v", verr.Conflicts)
+	}
+	if !reflect.DeepEqual(verr.BrokenCages, []int{1}) {
+		t.Errorf("expected cage 1 to be broken - got %v", verr.BrokenCages)
+	}
+	if !strings.Contains(err.Error(), "cage 2 at r2c1 cannot add up to 4") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if cells := verr.Cells(); !reflect.DeepEqual(cells, []int{0, 2, 9, 10}) {
+		t.Errorf("unexpected cells %v", cells)
+	}
+
+	errors := [][]Cage{
+		{{Sum: 3, Cells: []int{0, 1}}, {Sum: 3, Cells: []int{1, 2}}},
+		{{Sum: 3, Cells: []int{0, 81}}},
+		{{Sum: 3, Cells: []int{0, 0}}},
+		{{Sum: 2, Cells: []int{0, 1}}},
+		{{Sum: 5, Cells: nil}},
+	}
+	for _, cages := range errors {
+		b, _ := NewBoard(3, 3)
+		b.Cages = cages
+		if err := b.Validate(); err == nil {
+			t.Errorf("%v: expected an error", cages)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package solver
+
+import (
+	"context"
+	"errors"
+)
+
+// Errors returned when a puzzle could not be solved.
+var (
+	ErrUnsolvable = errors.New("puzzle has no solution")
+	ErrCancelled  = errors.New("solving was cancelled")
+	ErrTimedOut   = errors.New("solving timed out")
+)
+
+// how many steps a search takes between checks for cancellation
+const cancelCheckInterval = 256
+
+// canceller lets a search stop once its context is done.
+type canceller struct {
+	done    <-chan struct{}
+	steps   int
+	stopped bool
+}
+
+func newCanceller(ctx context.Context) canceller {
+	return canceller{done: ctx.Done()}
+}
+
+// stop returns true once the context is done. Checking the context on every
+// step would slow the search down, so it is only checked every so often.
+func (c *canceller) stop() bool {
+	if c.done == nil || c.stopped {
+		return c.stopped
+	}
+	c.steps++
+	if c.steps%cancelCheckInterval == 0 {
+		select {
+		case <-c.done:
+			c.stopped = true
+		default:
+		}
+	}
+	return c.stopped
+}
+
+// send sends an event to ch, giving up if the context is done first so that
+// the search can't block forever on a channel nobody reads.
+func (c *canceller) send(ch chan UpdateEvent, event UpdateEvent) {
+	if c.stopped {
+		return
+	}
+	if c.done == nil {
+		ch <- event
+		return
+	}
+	select {
+	case ch <- event:
+	case <-c.done:
+		c.stopped = true
+	}
+}
+
+// solveError returns the error for a search which did not find a solution.
+func solveError(ctx context.Context, stopped bool) error {
+	if !stopped {
+		return ErrUnsolvable
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimedOut
+	}
+	return ErrCancelled
+}
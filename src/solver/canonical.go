@@ -0,0 +1,147 @@
+package solver
+
+// canonicalizer searches for the transform which gives the lexicographically
+// smallest grid, building the result a row at a time and abandoning any
+// partial result which is already bigger than the best found so far.
+type canonicalizer struct {
+	grid Grid
+	best Grid
+	// the number of rows of best which partial results are compared with
+	valid int
+	// whether the current partial result is better than best
+	improved  bool
+	transform Transform
+}
+
+// partial is a partly built transform. Digits are relabelled in the order
+// they first appear in the result.
+type partial struct {
+	transpose bool
+	rows      [9]int
+	columns   [9]int
+	digits    [10]int
+	next      int
+	// the bands used so far
+	bands [3]bool
+}
+
+// cell returns the untransformed value at row and column.
+func (c *canonicalizer) cell(p *partial, row, column int) int {
+	if p.transpose {
+		row, column = column, row
+	}
+	return c.grid[row*9+column]
+}
+
+func (c *canonicalizer) search(p partial, k int) {
+	if k == 9 {
+		if !c.improved {
+			// only a tie - keep the transform already found
+			return
+		}
+		c.improved = false
+		c.transform = Transform{Transpose: p.transpose, Rows: p.rows, Columns: p.columns, Digits: p.digits}
+		// digits missing from the grid take the remaining labels in order
+		next := p.next
+		for d := 1; d <= 9; d++ {
+			if c.transform.Digits[d] == 0 {
+				c.transform.Digits[d] = next
+				next++
+			}
+		}
+		return
+	}
+
+	var rows []int
+	if k%3 == 0 {
+		for band := 0; band < 3; band++ {
+			if !p.bands[band] {
+				rows = append(rows, band*3, band*3+1, band*3+2)
+			}
+		}
+	} else {
+		band := p.rows[k-1] / 3
+		for row := band * 3; row < band*3+3; row++ {
+			used := false
+			for _, r := range p.rows[k-k%3 : k] {
+				used = used || r == row
+			}
+			if !used {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	for _, row := range rows {
+		q := p
+		q.rows[k] = row
+		q.bands[row/3] = true
+		var line [9]int
+		for column := 0; column < 9; column++ {
+			value := c.cell(&q, row, q.columns[column])
+			if value != 0 && q.digits[value] == 0 {
+				q.next++
+				q.digits[value] = q.next
+			}
+			line[column] = q.digits[value]
+		}
+
+		if k < c.valid {
+			compared := compareLines(line[:], c.best[k*9:k*9+9])
+			if compared > 0 {
+				continue
+			}
+			if compared < 0 {
+				c.valid = k
+			}
+		}
+		if k >= c.valid {
+			copy(c.best[k*9:k*9+9], line[:])
+			c.valid = k + 1
+			c.improved = true
+		}
+		c.search(q, k+1)
+	}
+}
+
+func compareLines(a, b []int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// CanonicalTransform returns the Transform which turns g into its canonical
+// form.
+func (g Grid) CanonicalTransform() Transform {
+	c := canonicalizer{grid: g}
+	for _, transpose := range []bool{false, true} {
+		for _, columns := range linePermutations {
+			c.search(partial{transpose: transpose, columns: columns}, 0)
+		}
+	}
+	return c.transform
+}
+
+// Canonical returns the canonical form of g, which is the same for every
+// grid which can be turned into g by a Transform. It is the lexicographically
+// smallest such grid, reading row by row with empty cells first, with digits
+// numbered in the order they first appear.
+func (g Grid) Canonical() Grid {
+	return g.CanonicalTransform().Apply(g)
+}
+
+// Equivalent reports whether a can be turned into b by a Transform, and
+// returns the Transform if it can.
+func Equivalent(a, b Grid) (Transform, bool) {
+	ta, tb := a.CanonicalTransform(), b.CanonicalTransform()
+	if ta.Apply(a) != tb.Apply(b) {
+		return Transform{}, false
+	}
+	return ta.Then(tb.Inverse()), true
+}
@@ -2,30 +2,27 @@ package solver
 
 type cellcontext struct {
 	index      int
-	nextEmpty  int
-	candidates []int
+	candidates digitMask
+
+	// length of the search trail before this cell was filled in
+	trail int
 }
 
-func newCellContext(index, nextEmpty int, candidates []int) cellcontext {
+func newCellContext(index int, candidates digitMask) cellcontext {
 	return cellcontext{
 		index:      index,
-		nextEmpty:  nextEmpty,
 		candidates: candidates,
 	}
 }
 
 func (c cellcontext) hasMoreCandidates() bool {
-	return len(c.candidates) > 0
+	return c.candidates != 0
 }
 
 // returns -1 if there are no more candidates
 func (c *cellcontext) nextCandidate() int {
-	candidateCount := len(c.candidates)
-	if candidateCount == 0 {
-		return -1
-	}
-	value := c.candidates[0]
-	c.candidates = c.candidates[1:]
+	value := c.candidates.lowest()
+	c.candidates &= c.candidates - 1
 	return value
 }
 
@@ -34,7 +31,8 @@ type stack struct {
 }
 
 func newStack() stack {
-	return stack{data: []cellcontext{}}
+	// a grid never has more than 81 empty cells, so the stack never has to grow
+	return stack{data: make([]cellcontext, 0, 81)}
 }
 
 func (s stack) hasMore() bool {
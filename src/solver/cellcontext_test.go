@@ -52,13 +52,13 @@ func TestCellContext(t *testing.T) {
 		hasMore bool
 		value   int
 	}{
-		{true, 0},
 		{true, 1},
 		{true, 2},
+		{true, 3},
 		{false, -1},
 	}
-	candidates := []int{0, 1, 2}
-	c := newCellContext(0, -1, candidates)
+	candidates := maskOf(1) | maskOf(2) | maskOf(3)
+	c := newCellContext(0, candidates)
 	for _, table := range tables {
 		if table.hasMore != c.hasMoreCandidates() {
 			t.Errorf("expected hasMoreCandidates() to return a value of %v - got %v instead", table.hasMore, c.hasMoreCandidates())
@@ -0,0 +1,73 @@
+package solver
+
+import "fmt"
+
+// AntiKnightConstraint is the rule that no two cells a chess knight's move
+// apart hold the same value.
+type AntiKnightConstraint struct{}
+
+var knightMoves = [][2]int{{-2, -1}, {-2, 1}, {-1, -2}, {-1, 2}, {1, -2}, {1, 2}, {2, -1}, {2, 1}}
+
+// Forbidden returns the values a knight's move from cell i.
+func (AntiKnightConstraint) Forbidden(b *Board, i int) uint32 {
+	return valuesIn(b, movesFrom(b.Size(), i, knightMoves))
+}
+
+// Check adds a Violation to e for every pair of cells a knight's move apart
+// holding the same value.
+func (AntiKnightConstraint) Check(b *Board, e *ValidationError) {
+	checkMoves(b, "anti-knight", "a knight's move apart", knightMoves, e)
+}
+
+// AntiKingConstraint is the rule that no two cells a chess king's move apart
+// hold the same value. Cells side by side already can't, so this means that
+// diagonally adjacent cells can't either.
+type AntiKingConstraint struct{}
+
+var kingMoves = [][2]int{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}}
+
+// Forbidden returns the values diagonally adjacent to cell i.
+func (AntiKingConstraint) Forbidden(b *Board, i int) uint32 {
+	return valuesIn(b, movesFrom(b.Size(), i, kingMoves))
+}
+
+// Check adds a Violation to e for every pair of diagonally adjacent cells
+// holding the same value.
+func (AntiKingConstraint) Check(b *Board, e *ValidationError) {
+	checkMoves(b, "anti-king", "diagonally adjacent", kingMoves, e)
+}
+
+// movesFrom returns the cells a move away from cell i on a board with size
+// rows, for moves given as row and column offsets.
+func movesFrom(size, i int, moves [][2]int) []int {
+	var cells []int
+	row, column := i/size, i%size
+	for _, move := range moves {
+		r, c := row+move[0], column+move[1]
+		if r >= 0 && r < size && c >= 0 && c < size {
+			cells = append(cells, r*size+c)
+		}
+	}
+	return cells
+}
+
+// checkMoves adds a Violation to e for every pair of cells a move apart
+// holding the same value. apart describes how the cells are placed.
+func checkMoves(b *Board, rule, apart string, moves [][2]int, e *ValidationError) {
+	size := b.Size()
+	for i, value := range b.Cells {
+		if value < 1 || value > size {
+			continue
+		}
+		for _, j := range movesFrom(size, i, moves) {
+			// report each pair once, from its first cell
+			if j > i && b.Cells[j] == value {
+				e.Violations = append(e.Violations, Violation{
+					Rule:  rule,
+					Cells: []int{i, j},
+					Msg:   fmt.Sprintf("%c at %s and %s are %s", symbol(value), cellNameOn(i, size), cellNameOn(j, size), apart),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package solver
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChessForbidden(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	// r3c2 and r2c3 are a knight's move from r1c1, r2c2 a king's move
+	b.Cells[19], b.Cells[11], b.Cells[10] = 4, 7, 2
+	if forbidden := (AntiKnightConstraint{}).Forbidden(b, 0); forbidden != 0x48 {
+		t.Errorf("expected 4 and 7 to be forbidden - got %x", forbidden)
+	}
+	if forbidden := (AntiKingConstraint{}).Forbidden(b, 0); forbidden != 0x2 {
+		t.Errorf("expected 2 to be forbidden - got %x", forbidden)
+	}
+	if cells := movesFrom(9, 40, knightMoves); len(cells) != 8 {
+		t.Errorf("expected 8 knight's moves from the centre - got %v", cells)
+	}
+}
+
+func TestChessValidate(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	b.Constraints = []Constraint{AntiKnightConstraint{}, AntiKingConstraint{}}
+	b.Cells[0], b.Cells[11] = 5, 5
+	b.Cells[40], b.Cells[50] = 3, 3
+	err := b.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError - got %v", err)
+	}
+	expected := []Violation{
+		{"anti-knight", []int{0, 11}, "5 at r1c1 and r2c3 are a knight's move apart"},
+		{"anti-king", []int{40, 50}, "3 at r5c5 and r6c6 are diagonally adjacent"},
+	}
+	if !reflect.DeepEqual(verr.Violations, expected) {
+		t.Errorf("expected %v - got %v instead", expected, verr.Violations)
+	}
+	if !strings.Contains(err.Error(), expected[0].Msg) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestAntiKnightEmpty(t *testing.T) {
+	// an empty board has plenty of solutions, but a search which guesses
+	// badly can take minutes to find one
+	b, _ := NewBoard(3, 3)
+	b.Constraints = []Constraint{AntiKnightConstraint{}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := b.Solve(ctx, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := b.Validate(); err != nil {
+		t.Errorf("invalid solution %s: %v", b, err)
+	}
+}
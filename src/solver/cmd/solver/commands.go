@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"solver"
+	"sort"
+	"strings"
+	"time"
+)
+
+// command is a CLI subcommand.
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"solve", "solve a puzzle and print the solution", runSolve},
+		{"solutions", "print every solution to a puzzle, one per line", runSolutions},
+		{"rate", "rate how hard a puzzle is to solve by logic", runRate},
+		{"generate", "generate puzzles, one per line", runGenerate},
+		{"scramble", "print shuffled copies of a puzzle, one per line", runScramble},
+		{"library", "import, list and export puzzles in a puzzle library", runLibrary},
+	}
+}
+
+func isCommand(name string) bool {
+	for _, c := range commands {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommand runs the named subcommand and returns the process exit code.
+func runCommand(name string, args []string) int {
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		if err := c.run(args); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %s\n", name)
+	return 2
+}
+
+// readPuzzle parses the puzzle in the first argument, which may be the name
+// of a file holding the puzzle. The puzzle is read from stdin if there are no
+// arguments.
+func readPuzzle(args []string) (solver.Grid, error) {
+	text, name, err := readInput(args)
+	if err != nil {
+		return solver.Grid{}, err
+	}
+	grid, err := solver.ParseGrid(text)
+	if err != nil && name != "" {
+		return grid, fmt.Errorf("%s: %v", name, err)
+	}
+	return grid, err
+}
+
+// readBoard is like readPuzzle for puzzles of any size. box gives the shape
+// of the boxes, such as 2x3, or is empty to work it out from the puzzle.
+// layout, if not empty, is a jigsaw layout or the file holding one, and
+// replaces the boxes, so giving both is an error. Puzzles written as JSON,
+// such as Killer Sudokus with their cages, give their own box shape or
+// layout, so giving either with them is an error too.
+func readBoard(args []string, box, layout string) (*solver.Board, error) {
+	text, name, err := readInput(args)
+	if err != nil {
+		return nil, err
+	}
+	board, err := parseBoard(text, box, layout)
+	if err != nil && name != "" {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return board, err
+}
+
+func parseBoard(text, box, layout string) (*solver.Board, error) {
+	if strings.HasPrefix(strings.TrimSpace(text), "{") {
+		if box != "" || layout != "" {
+			return nil, fmt.Errorf("a JSON puzzle gives its own box shape or layout - leave out -box and -layout")
+		}
+		board := &solver.Board{}
+		if err := json.Unmarshal([]byte(text), board); err != nil {
+			return nil, err
+		}
+		return board, nil
+	}
+	if layout != "" {
+		if box != "" {
+			return nil, fmt.Errorf("a jigsaw layout replaces the boxes - give -layout or -box, not both")
+		}
+		regions, _, err := readInput([]string{layout})
+		if err != nil {
+			return nil, err
+		}
+		return solver.ParseJigsaw(text, regions)
+	}
+	boxRows, boxColumns := 0, 0
+	if box != "" {
+		var err error
+		if boxRows, boxColumns, err = solver.ParseBoxShape(box); err != nil {
+			return nil, err
+		}
+	}
+	return solver.ParseBoard(text, boxRows, boxColumns)
+}
+
+// readInput returns the text of the first argument, or of the file it names,
+// along with the file name. The text is read from stdin if there are no
+// arguments.
+func readInput(args []string) (text, name string, err error) {
+	if len(args) == 0 {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return "", "", fmt.Errorf("could not read puzzle from stdin: %v", err)
+		}
+		return string(b), "", nil
+	}
+	if _, err := os.Stat(args[0]); err == nil {
+		b, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return "", "", fmt.Errorf("could not read puzzle from %s: %v", args[0], err)
+		}
+		return string(b), args[0], nil
+	}
+	return args[0], "", nil
+}
+
+// solverFlags are the flags for picking a solving algorithm.
+type solverFlags struct {
+	algorithm, order *string
+	propagate        *bool
+}
+
+// addSolverFlags registers the flags for picking a solving algorithm.
+func addSolverFlags(flags *flag.FlagSet) solverFlags {
+	return solverFlags{
+		algorithm: flags.String("algorithm", "backtrack", "Solving algorithm - backtrack or dlx."),
+		order:     flags.String("order", "sequential", "Cell order for the backtracker - sequential or mrv."),
+		propagate: flags.Bool("propagate", false, "Deduce naked and hidden singles while backtracking."),
+	}
+}
+
+// newSolver builds the solver once the flags are parsed.
+func (f solverFlags) newSolver() (solver.Solver, error) {
+	order, err := solver.ParseCellOrder(*f.order)
+	if err != nil {
+		return nil, err
+	}
+	return solver.NewSolver(*f.algorithm, solver.Options{Order: order, Propagate: *f.propagate})
+}
+
+// checkBoardOptions returns an error unless the solving options are left at
+// their defaults. Only plain 9x9 grids can be solved with other algorithms
+// and options - the rest are always solved by Board.Solve.
+func checkBoardOptions(algorithm string, order solver.CellOrder, propagate bool) error {
+	if (algorithm != "" && algorithm != "backtrack") || order != solver.Sequential || propagate {
+		return fmt.Errorf("the algorithm, order and propagate options only apply to 9x9 puzzles without regions, cages or variants")
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseDifficulties parses a comma separated list of difficulties.
+func parseDifficulties(list string) ([]solver.Difficulty, error) {
+	var difficulties []solver.Difficulty
+	for _, name := range splitList(list) {
+		d, err := solver.ParseDifficulty(name)
+		if err != nil {
+			return nil, err
+		}
+		difficulties = append(difficulties, d)
+	}
+	return difficulties, nil
+}
+
+// generateOptions builds the options for generating puzzles from a comma
+// separated list of difficulties and a symmetry name. Either may be empty.
+func generateOptions(difficulties, symmetry string, minClues, maxClues int) (solver.GenerateOptions, error) {
+	options := solver.GenerateOptions{MinClues: minClues, MaxClues: maxClues}
+	var err error
+	if options.Difficulties, err = parseDifficulties(difficulties); err != nil {
+		return options, err
+	}
+	options.Symmetry, err = solver.ParseSymmetry(symmetry)
+	return options, err
+}
+
+// libraryFilter builds a filter for library entries from comma separated
+// lists of tags and difficulties. Either may be empty.
+func libraryFilter(tags, difficulties string) (solver.LibraryFilter, error) {
+	filter := solver.LibraryFilter{Tags: splitList(tags)}
+	var err error
+	filter.Difficulties, err = parseDifficulties(difficulties)
+	return filter, err
+}
+
+func runSolve(args []string) error {
+	flags := flag.NewFlagSet("solve", flag.ExitOnError)
+	solverOptions := addSolverFlags(flags)
+	timeout := flags.Duration("timeout", 0, "Give up after this long - 0 for no limit.")
+	box := flags.String("box", "", "Box shape for puzzles which aren't 9x9, such as 2x3 - worked out from the puzzle if not given.")
+	layout := flags.String("layout", "", "Jigsaw layout, or a file holding one, giving the region of each cell - replaces the boxes.")
+	variants := flags.String("variants", "", "Comma separated list of variants - x for X-Sudoku, windoku, antiknight, antiking or nonconsecutive.")
+	flags.Parse(args)
+
+	s, err := solverOptions.newSolver()
+	if err != nil {
+		return err
+	}
+	board, err := readBoard(flags.Args(), *box, *layout)
+	if err != nil {
+		return err
+	}
+	constraints, err := solver.ParseVariants(*variants)
+	if err != nil {
+		return err
+	}
+	board.Constraints = append(board.Constraints, constraints...)
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	grid, err := board.Grid()
+	if err != nil {
+		// newSolver has already checked the order
+		order, _ := solver.ParseCellOrder(*solverOptions.order)
+		if err := checkBoardOptions(*solverOptions.algorithm, order, *solverOptions.propagate); err != nil {
+			return err
+		}
+		if err := board.Solve(ctx, nil); err != nil {
+			return err
+		}
+		board.Print(os.Stdout)
+		return nil
+	}
+	if err := s.Solve(ctx, &grid, nil); err != nil {
+		return err
+	}
+	grid.Print(os.Stdout)
+	return nil
+}
+
+func runSolutions(args []string) error {
+	flags := flag.NewFlagSet("solutions", flag.ExitOnError)
+	max := flags.Int("max", 0, "Stop after printing this many solutions - 0 prints every solution.")
+	flags.Parse(args)
+
+	grid, err := readPuzzle(flags.Args())
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	count := 0
+	grid.EachSolution(func(solution solver.Grid) bool {
+		fmt.Fprintln(out, solution)
+		count++
+		return *max <= 0 || count < *max
+	})
+	return nil
+}
+
+func runRate(args []string) error {
+	flags := flag.NewFlagSet("rate", flag.ExitOnError)
+	flags.Parse(args)
+
+	grid, err := readPuzzle(flags.Args())
+	if err != nil {
+		return err
+	}
+	rating, err := grid.Rate()
+	if err != nil {
+		return err
+	}
+	fmt.Println(rating)
+	return nil
+}
+
+func runGenerate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	count := flags.Int("n", 1, "Number of puzzles to generate.")
+	seed := flags.Int64("seed", 0, "Random seed - 0 picks one from the clock.")
+	difficulty := flags.String("difficulty", "", "Comma separated difficulties to accept - easy, medium, hard, fiendish or diabolical.")
+	symmetry := flags.String("symmetry", "none", "Symmetry of the givens - none, rot180, mirror, diagonal or rot90.")
+	minClues := flags.Int("minclues", 0, "Minimum number of givens - 0 for no limit.")
+	maxClues := flags.Int("maxclues", 0, "Maximum number of givens - 0 for no limit.")
+	attempts := flags.Int("attempts", 0, "Puzzles to try for each one printed - 0 for the default.")
+	timeout := flags.Duration("timeout", time.Minute, "Give up on a puzzle after this long - 0 for no limit.")
+	flags.Parse(args)
+
+	options, err := generateOptions(*difficulty, *symmetry, *minClues, *maxClues)
+	if err != nil {
+		return err
+	}
+	options.MaxAttempts = *attempts
+	options.Timeout = *timeout
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	g := solver.NewGenerator(*seed)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for i := 0; i < *count; i++ {
+		grid, err := g.GenerateWithOptions(options)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, grid)
+	}
+	return nil
+}
+
+func runScramble(args []string) error {
+	flags := flag.NewFlagSet("scramble", flag.ExitOnError)
+	count := flags.Int("n", 1, "Number of copies to print.")
+	seed := flags.Int64("seed", 0, "Random seed - 0 picks one from the clock.")
+	flags.Parse(args)
+
+	grid, err := readPuzzle(flags.Args())
+	if err != nil {
+		return err
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(*seed))
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for i := 0; i < *count; i++ {
+		fmt.Fprintln(out, solver.RandomTransform(r).Apply(grid))
+	}
+	return nil
+}
+
+// the library used when neither -db nor LIBRARY is set
+const defaultLibrary = "puzzles.jsonl"
+
+func runLibrary(args []string) error {
+	flags := flag.NewFlagSet("library", flag.ExitOnError)
+	db := defaultLibrary
+	if env := os.Getenv("LIBRARY"); env != "" {
+		db = env
+	}
+	flags.StringVar(&db, "db", db, "Library file - overrides the LIBRARY environment variable.")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: solver library [-db file] import|list|export|tags [flags] [files]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing library command")
+	}
+
+	l, err := solver.OpenLibrary(db)
+	if err != nil {
+		return err
+	}
+	args = flags.Args()
+	switch args[0] {
+	case "import":
+		return runLibraryImport(l, args[1:])
+	case "list", "export":
+		return runLibraryList(l, args[0], args[1:])
+	case "tags":
+		tags := l.Tags()
+		var names []string
+		for name := range tags {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s\t%d\n", name, tags[name])
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown library command %s", args[0])
+}
+
+func runLibraryImport(l *solver.Library, args []string) error {
+	flags := flag.NewFlagSet("library import", flag.ExitOnError)
+	source := flags.String("source", "", "Where the puzzles came from.")
+	tags := flags.String("tags", "", "Comma separated tags for every puzzle.")
+	difficulty := flags.String("difficulty", "", "Difficulty of every puzzle - rated if not given.")
+	flags.Parse(args)
+
+	options := solver.ImportOptions{Source: *source, Tags: splitList(*tags)}
+	if *difficulty != "" {
+		d, err := solver.ParseDifficulty(*difficulty)
+		if err != nil {
+			return err
+		}
+		options.Difficulty = &d
+	}
+	if flags.NArg() == 0 {
+		result, err := l.Import(os.Stdin, options)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("stdin: added %d, %d duplicates, %d rejected\n", result.Added, result.Duplicates, result.Rejected)
+		return nil
+	}
+	for _, file := range flags.Args() {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		result, err := l.Import(f, options)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		fmt.Printf("%s: added %d, %d duplicates, %d rejected\n", file, result.Added, result.Duplicates, result.Rejected)
+	}
+	return nil
+}
+
+// runLibraryList lists entries with their metadata, or exports them as
+// puzzles only or as JSON lines.
+func runLibraryList(l *solver.Library, name string, args []string) error {
+	flags := flag.NewFlagSet("library "+name, flag.ExitOnError)
+	tags := flags.String("tag", "", "Comma separated tags which puzzles must all have.")
+	difficulty := flags.String("difficulty", "", "Comma separated difficulties to include.")
+	format := flags.String("format", "sdm", "Export format - sdm for one puzzle per line or json for JSON lines.")
+	flags.Parse(args)
+
+	filter, err := libraryFilter(*tags, *difficulty)
+	if err != nil {
+		return err
+	}
+	if name == "export" && *format != "sdm" && *format != "json" {
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	encoder := json.NewEncoder(out)
+	for _, entry := range l.Entries(filter) {
+		switch {
+		case name == "list":
+			fmt.Fprintf(out, "%s %-10s %.1f %s\n", entry.Puzzle, entry.Difficulty, entry.Rating, strings.Join(entry.Tags, ","))
+		case *format == "json":
+			encoder.Encode(entry)
+		default:
+			fmt.Fprintln(out, entry.Puzzle)
+		}
+	}
+	return nil
+}
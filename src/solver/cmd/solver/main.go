@@ -2,12 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"solver"
 	"solver/helper"
@@ -26,33 +28,134 @@ const staticFilename = "debug.html"
 
 var upgrader = websocket.Upgrader{}
 var debug = false
+var timeLimit = 10 * time.Minute
+var puzzleSource solver.PuzzleSource
+var library *solver.Library
+
+// how long /puzzle keeps looking for a puzzle matching the query
+const generateTimeLimit = 10 * time.Second
+
+var generator = solver.NewGeneratorSource(time.Now().UnixNano(), solver.GenerateOptions{})
 
 func handler(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	log.Print("Request for URI: ", path)
 	if path == "/puzzle" {
 		w.Header().Set("Content-Type", "application/json")
-		grid, err := solver.LoadPuzzle()
+		query := r.URL.Query()
+		var maxClues int
+		minClues, err := clueCount(query, "minclues")
+		if err == nil {
+			maxClues, err = clueCount(query, "maxclues")
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			outputError(w, err)
+			return
+		}
+		grid, err := loadPuzzle(r.Context(), query, minClues, maxClues)
+		if err != nil {
+			outputError(w, err)
+			return
+		}
+		reply := struct {
+			Puzzle string         `json:"puzzle"`
+			Rating *solver.Rating `json:"rating,omitempty"`
+		}{Puzzle: grid.String()}
+		if rating, err := grid.Rate(); err == nil {
+			reply.Rating = &rating
+		}
+		json.NewEncoder(w).Encode(reply)
+		return
+	}
+
+	if strings.HasPrefix(path, "/check/") {
+		w.Header().Set("Content-Type", "application/json")
+		board, err := boardFromPath(path[len("/check/"):], r.URL.Query())
 		if err != nil {
 			outputError(w, err)
 			return
 		}
-		fmt.Fprintf(w, "{\"puzzle\":\"%s\"}", grid)
+		if err := board.Validate(); err != nil {
+			outputError(w, err)
+			return
+		}
+		// we only need to know whether there is more than one solution
+		var count int
+		if grid, err := board.Grid(); err == nil {
+			count = grid.CountSolutions(2)
+		} else {
+			ctx := r.Context()
+			if timeLimit > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeLimit)
+				defer cancel()
+			}
+			if count, err = board.CountSolutions(ctx, 2); err != nil {
+				outputError(w, fmt.Errorf("could not check the puzzle - %v", err))
+				return
+			}
+		}
+		fmt.Fprintf(w, "{\"solutions\":%d,\"unique\":%t}", count, count == 1)
+		return
+	}
+
+	if strings.HasPrefix(path, "/explain/") {
+		w.Header().Set("Content-Type", "application/json")
+		grid, err := gridFromPath(path[len("/explain/"):])
+		if err != nil {
+			outputError(w, err)
+			return
+		}
+		explanation, err := grid.Explain()
+		if err != nil {
+			outputError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(explanation)
+		return
+	}
+
+	if path == "/solve" {
+		solveBoard(w, r)
 		return
 	}
 
 	if strings.HasPrefix(path, "/solve/") {
-		puzzle := path[len("/solve/"):]
-		if len(puzzle) != 81 {
-			outputError(w, fmt.Errorf("puzzle did not have the expected length of 81 - received %d instead", len(puzzle)))
+		query := r.URL.Query()
+		board, err := boardFromPath(path[len("/solve/"):], query)
+		if err != nil {
+			outputError(w, err)
+			return
+		}
+		// report problems before opening the websocket
+		if err := board.Validate(); err != nil {
+			outputError(w, err)
+			return
+		}
+		order, err := solver.ParseCellOrder(query.Get("order"))
+		if err != nil {
+			outputError(w, err)
 			return
 		}
-		grid, err := solver.NewGridFromString(puzzle)
+		propagate := query.Get("propagate") == "true"
+		grid, err := board.Grid()
 		if err != nil {
-			outputError(w, fmt.Errorf("could not convert %s to Grid object: %v", puzzle, err))
+			if err := checkBoardOptions(query.Get("algorithm"), order, propagate); err != nil {
+				outputError(w, err)
+				return
+			}
+			handleSolveRequest(w, r, board.Solve)
 			return
 		}
-		handleSolveRequest(w, r, grid)
+		s, err := solver.NewSolver(query.Get("algorithm"), solver.Options{Order: order, Propagate: propagate})
+		if err != nil {
+			outputError(w, err)
+			return
+		}
+		handleSolveRequest(w, r, func(ctx context.Context, ch chan solver.UpdateEvent) error {
+			return s.Solve(ctx, &grid, ch)
+		})
 		return
 	}
 
@@ -65,13 +168,225 @@ func handler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Not Found"))
 }
 
-func handleSolveRequest(w http.ResponseWriter, r *http.Request, grid solver.Grid) {
+// loadPuzzle returns a new puzzle from the configured source. Queries asking
+// for a tag draw from the library. Queries asking for a difficulty draw from
+// the library if it has a matching puzzle, and otherwise use the generator,
+// as do queries asking for a symmetry or number of clues. A minClues or
+// maxClues of 0 means no limit.
+func loadPuzzle(ctx context.Context, query url.Values, minClues, maxClues int) (solver.Grid, error) {
+	tag, difficulty, symmetry := query.Get("tag"), query.Get("difficulty"), query.Get("symmetry")
+	if tag == "" && difficulty == "" && symmetry == "" && minClues == 0 && maxClues == 0 {
+		return puzzleSource.Puzzle(ctx)
+	}
+	if tag != "" || (library != nil && symmetry == "" && minClues == 0 && maxClues == 0) {
+		grid, err := drawFromLibrary(tag, difficulty)
+		// only tags need the library
+		if err == nil || tag != "" {
+			return grid, err
+		}
+	}
+	options, err := generateOptions(difficulty, symmetry, minClues, maxClues)
+	if err != nil {
+		return solver.Grid{}, err
+	}
+	options.Timeout = generateTimeLimit
+	return generator.PuzzleWithOptions(ctx, options)
+}
+
+// clueCount reads the number of clues in the named query parameter, which is
+// 0 if it is missing.
+func clueCount(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("could not read %s: %v", name, err)
+	}
+	return count, nil
+}
+
+// drawFromLibrary returns a random puzzle from the library with every tag and
+// any of the difficulties in the comma separated lists.
+func drawFromLibrary(tags, difficulties string) (solver.Grid, error) {
+	if library == nil {
+		return solver.Grid{}, fmt.Errorf("there is no puzzle library to draw tagged puzzles from")
+	}
+	filter, err := libraryFilter(tags, difficulties)
+	if err != nil {
+		return solver.Grid{}, err
+	}
+	entry, err := library.Random(filter)
+	if err != nil {
+		return solver.Grid{}, err
+	}
+	return solver.NewGridFromString(entry.Puzzle)
+}
+
+// newPuzzleSource builds the puzzle source from a comma separated list of
+// sources, which are tried in order until one supplies a puzzle. Each source
+// is generator, davidbau, library, an http or https URL serving JSON with a
+// puzzle field, or file: followed by the path to a file or directory of
+// puzzles.
+func newPuzzleSource(spec string) (solver.PuzzleSource, error) {
+	var sources solver.FallbackSource
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "generator":
+			sources = append(sources, generator)
+		case name == "davidbau":
+			sources = append(sources, solver.DavidBauSource{})
+		case name == "library":
+			if library == nil {
+				return nil, fmt.Errorf("the library source needs -library or LIBRARY to be set")
+			}
+			sources = append(sources, library)
+		case strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://"):
+			sources = append(sources, solver.HTTPSource{URL: name, Client: &http.Client{Timeout: 30 * time.Second}})
+		case strings.HasPrefix(name, "file:"):
+			source, err := solver.NewFileSource(name[len("file:"):], time.Now().UnixNano())
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("Loaded %d puzzles from %s", source.Len(), name[len("file:"):])
+			sources = append(sources, source)
+		default:
+			return nil, fmt.Errorf("unknown puzzle source %s", name)
+		}
+	}
+	if len(sources) == 1 {
+		return sources[0], nil
+	}
+	return sources, nil
+}
+
+func gridFromPath(puzzle string) (solver.Grid, error) {
+	grid, err := solver.ParseGrid(puzzle)
+	if err != nil {
+		return grid, fmt.Errorf("could not convert %s to Grid object: %v", puzzle, err)
+	}
+	return grid, nil
+}
+
+// boardFromPath parses a puzzle of any size. The box query parameter gives
+// the shape of the boxes, such as 2x3 - otherwise it is worked out from the
+// number of cells. The layout parameter gives the regions of a jigsaw
+// puzzle, the cages parameter holds the cages of a Killer Sudoku as a JSON
+// array, the variants parameter lists built-in variants such as x,windoku,
+// and the kropki, thermometers, arrows and sandwiches parameters hold those
+// constraints as JSON, in the form of the matching fields of a puzzle written
+// as JSON.
+func boardFromPath(puzzle string, query url.Values) (*solver.Board, error) {
+	var board *solver.Board
+	var err error
+	if layout := query.Get("layout"); layout != "" {
+		board, err = solver.ParseJigsaw(puzzle, layout)
+	} else {
+		boxRows, boxColumns := 0, 0
+		if box := query.Get("box"); box != "" {
+			if boxRows, boxColumns, err = solver.ParseBoxShape(box); err != nil {
+				return nil, err
+			}
+		}
+		board, err = solver.ParseBoard(puzzle, boxRows, boxColumns)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not convert %s to Board object: %v", puzzle, err)
+	}
+	if cages := query.Get("cages"); cages != "" {
+		if err := json.Unmarshal([]byte(cages), &board.Cages); err != nil {
+			return nil, fmt.Errorf("could not read cages: %v", err)
+		}
+	}
+	variants, err := solver.ParseVariants(query.Get("variants"))
+	if err != nil {
+		return nil, err
+	}
+	board.Constraints = append(board.Constraints, variants...)
+	if dots := query.Get("kropki"); dots != "" {
+		var kropki solver.KropkiConstraint
+		if err := json.Unmarshal([]byte(dots), &kropki); err != nil {
+			return nil, fmt.Errorf("could not read Kropki dots: %v", err)
+		}
+		board.Constraints = append(board.Constraints, kropki)
+	}
+	if text := query.Get("thermometers"); text != "" {
+		var thermometers [][]int
+		if err := json.Unmarshal([]byte(text), &thermometers); err != nil {
+			return nil, fmt.Errorf("could not read thermometers: %v", err)
+		}
+		board.Constraints = append(board.Constraints, solver.ThermometerConstraint{Thermometers: thermometers})
+	}
+	if text := query.Get("arrows"); text != "" {
+		var arrows []solver.Arrow
+		if err := json.Unmarshal([]byte(text), &arrows); err != nil {
+			return nil, fmt.Errorf("could not read arrows: %v", err)
+		}
+		board.Constraints = append(board.Constraints, solver.ArrowConstraint{Arrows: arrows})
+	}
+	if text := query.Get("sandwiches"); text != "" {
+		var sandwiches solver.SandwichConstraint
+		if err := json.Unmarshal([]byte(text), &sandwiches); err != nil {
+			return nil, fmt.Errorf("could not read sandwich clues: %v", err)
+		}
+		board.Constraints = append(board.Constraints, sandwiches)
+	}
+	return board, nil
+}
+
+// solveBoard solves a puzzle posted as JSON, in the form accepted by
+// Board.UnmarshalJSON, and replies with the solution.
+func solveBoard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		outputError(w, fmt.Errorf("expected a POST with the puzzle as JSON"))
+		return
+	}
+	var board solver.Board
+	if err := json.NewDecoder(r.Body).Decode(&board); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		outputError(w, fmt.Errorf("could not read puzzle: %v", err))
+		return
+	}
+	ctx := r.Context()
+	if timeLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeLimit)
+		defer cancel()
+	}
+	if err := board.Solve(ctx, nil); err != nil {
+		outputError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(struct {
+		Solution string `json:"solution"`
+	}{board.String()})
+}
+
+// handleSolveRequest streams the updates made by solve to the browser. Each
+// update takes three bytes - the high and low bytes of the cell index, then
+// the value.
+func handleSolveRequest(w http.ResponseWriter, r *http.Request, solve func(ctx context.Context, ch chan solver.UpdateEvent) error) {
 	c, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		outputError(w, fmt.Errorf("could not upgrade to websocket: %v", err))
 		return
 	}
 	defer c.Close()
+
+	// stop solving if the browser goes away or the time limit runs out
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeLimit > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeLimit)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+
 	updatech := make(chan solver.UpdateEvent, bufferSize-1)
 	delaych := make(chan int)
 	go func(ch chan int, c *websocket.Conn) {
@@ -86,17 +401,21 @@ func handleSolveRequest(w http.ResponseWriter, r *http.Request, grid solver.Grid
 			p := payload[0]
 			if p < 11 {
 				delay := int(p)
-				ch <- delay
+				select {
+				case ch <- delay:
+				case <-ctx.Done():
+				}
 			}
 		}
 		log.Println("Terminating delay goroutine")
+		cancel()
 	}(delaych, c)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func(ch chan solver.UpdateEvent, c *websocket.Conn) {
 		delay := defaultDelay
-		var message [bufferSize * 2]byte
+		var message [bufferSize * 3]byte
 		keepgoing := true
 		count := 1
 		for keepgoing {
@@ -107,8 +426,9 @@ func handleSolveRequest(w http.ResponseWriter, r *http.Request, grid solver.Grid
 					keepgoing = false
 					break
 				}
-				message[0] = byte(event.Index)
-				message[1] = byte(event.Value)
+				message[0] = byte(event.Index >> 8)
+				message[1] = byte(event.Index)
+				message[2] = byte(event.Value)
 				count = 1
 				// more messages in the queue - let's fill it up
 				depth := len(updatech)
@@ -121,12 +441,18 @@ func handleSolveRequest(w http.ResponseWriter, r *http.Request, grid solver.Grid
 							break
 						} else {
 							count++
-							message[i*2] = byte(event.Index)
-							message[i*2+1] = byte(event.Value)
+							message[i*3] = byte(event.Index >> 8)
+							message[i*3+1] = byte(event.Index)
+							message[i*3+2] = byte(event.Value)
 						}
 					}
 				}
-				c.WriteMessage(websocket.BinaryMessage, message[:count*2])
+				if err := c.WriteMessage(websocket.BinaryMessage, message[:count*3]); err != nil {
+					log.Printf("Could not write to websocket: %v", err)
+					cancel()
+					keepgoing = false
+					break
+				}
 				time.Sleep(time.Duration(delay*100) * time.Duration(time.Microsecond))
 			case delayEvent, ok := <-delaych:
 				if !ok {
@@ -135,28 +461,50 @@ func handleSolveRequest(w http.ResponseWriter, r *http.Request, grid solver.Grid
 				}
 				delay = delayEvent
 				log.Printf("Setting delay to %d", delay)
+			case <-ctx.Done():
+				keepgoing = false
 			}
 		}
 		log.Println("Terminating update goroutine")
 		wg.Done()
 	}(updatech, c)
 
-	grid.Solve(updatech)
-	log.Println("Done solving the puzzle")
-	updatech <- solver.UpdateEvent{Index: -1}
+	err = solve(ctx, updatech)
+	if err != nil {
+		log.Printf("Stopped solving the puzzle: %v", err)
+	} else {
+		log.Println("Done solving the puzzle")
+	}
+	select {
+	case updatech <- solver.UpdateEvent{Index: -1}:
+	case <-ctx.Done():
+	}
 
 	wg.Wait()
-	close(updatech)
-	close(delaych)
+	if err != nil {
+		// let the browser know why the updates stopped
+		c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, err.Error()))
+	}
 	log.Println("Request done")
 }
 
 func outputError(w http.ResponseWriter, err error) {
-	fmt.Fprint(w, `{"error":`)
-	output, _ := json.Marshal(err)
-	buffer := bytes.NewBufferString(string(output))
+	reply := struct {
+		Error     string            `json:"error"`
+		Cells     []int             `json:"cells,omitempty"`
+		Conflicts []solver.Conflict `json:"conflicts,omitempty"`
+		// violations of variant rules other than repeated values
+		Violations []solver.Violation `json:"violations,omitempty"`
+	}{Error: err.Error()}
+	if verr, ok := err.(*solver.ValidationError); ok {
+		reply.Cells = verr.Cells()
+		reply.Conflicts = verr.Conflicts
+		reply.Violations = verr.Violations
+	}
+	output, _ := json.Marshal(reply)
+	buffer := bytes.NewBuffer(output)
 	buffer.WriteTo(w)
-	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
 }
 
 func staticContent(w io.Writer) {
@@ -189,6 +537,10 @@ func staticContent(w io.Writer) {
 }
 
 func main() {
+	if len(os.Args) > 1 && isCommand(os.Args[1]) {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
 	port := 0
 	portenv := os.Getenv("PORT")
 	if len(portenv) > 0 {
@@ -206,8 +558,41 @@ func main() {
 		flag.BoolVar(&debug, "debug", debug, "Use debug.html instead of the hardcoded statichtml.go file.")
 	}
 
+	timelimitenv := os.Getenv("TIMELIMIT")
+	if len(timelimitenv) > 0 {
+		var err error
+		if timeLimit, err = time.ParseDuration(timelimitenv); err != nil {
+			log.Fatalf("Could not read TIMELIMIT: %v", err)
+		}
+	} else {
+		flag.DurationVar(&timeLimit, "timelimit", timeLimit, "Maximum time spent solving a puzzle - 0 for no limit.")
+	}
+
+	source := "generator"
+	sourceenv := os.Getenv("SOURCE")
+	if len(sourceenv) > 0 {
+		source = sourceenv
+	} else {
+		flag.StringVar(&source, "source", source, "Where new puzzles come from - a comma separated list of generator, davidbau, file:path or an http URL, tried in order.")
+	}
+
+	libraryPath := os.Getenv("LIBRARY")
+	if len(libraryPath) == 0 {
+		flag.StringVar(&libraryPath, "library", libraryPath, "Puzzle library file, for /puzzle?tag= and the library source.")
+	}
+
 	flag.Parse()
 
+	var err error
+	if len(libraryPath) > 0 {
+		if library, err = solver.OpenLibrary(libraryPath); err != nil {
+			log.Fatalf("Could not open puzzle library: %v", err)
+		}
+		log.Printf("Loaded %d puzzles from library %s", library.Len(), libraryPath)
+	}
+	if puzzleSource, err = newPuzzleSource(source); err != nil {
+		log.Fatalf("Could not set up puzzle source: %v", err)
+	}
 	if debug {
 		log.Print("Debug mode on")
 	}
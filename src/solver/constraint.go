@@ -0,0 +1,155 @@
+package solver
+
+// Constraint is a rule which the values on a Board must follow, such as no
+// row holding a value twice. Every board has the row, column and box
+// constraints, and variants add more. Only Board takes constraints - Grid
+// and its solvers are for the usual rules alone.
+type Constraint interface {
+	// Forbidden returns the values which cell i can't hold given the values
+	// on b, with value v in bit v-1.
+	Forbidden(b *Board, i int) uint32
+	// Check adds every way the values on b break the constraint to e.
+	Check(b *Board, e *ValidationError)
+}
+
+// groupConstraint is a Constraint which only asks for the cells of each of
+// its groups to hold different values. The search keeps track of the values
+// in each group as it goes rather than calling Forbidden, and groups with as
+// many cells as a row must hold every value.
+type groupConstraint interface {
+	Constraint
+	groups(b *Board) [][]int
+}
+
+// preparedConstraint is a Constraint which works out what it needs from the
+// shape of a board once, before a search asks it about many positions.
+type preparedConstraint interface {
+	Constraint
+	// prepare returns a Constraint which behaves the same on boards
+	// shaped like b, but answers faster.
+	prepare(b *Board) Constraint
+}
+
+// narrowingConstraint is a Constraint which can rule out more values once it
+// knows the values every empty cell could still hold, rather than only the
+// values on the board.
+type narrowingConstraint interface {
+	Constraint
+	// narrow removes the values which would break the constraint from
+	// candidates, the values each empty cell of b could hold. It returns
+	// false if that leaves a cell without any.
+	narrow(b *Board, candidates []uint32) bool
+}
+
+// shapedConstraint is a Constraint given in a puzzle, such as Kropki dots or
+// thermometers, which has to fit on the board.
+type shapedConstraint interface {
+	Constraint
+	// checkShape returns an error unless the constraint fits on a board
+	// with size rows.
+	checkShape(size int) error
+}
+
+// RowConstraint is the rule that no row holds a value twice.
+type RowConstraint struct{}
+
+// Forbidden returns the values in the row of cell i.
+func (RowConstraint) Forbidden(b *Board, i int) uint32 {
+	return valuesIn(b, b.layout().units[i/b.Size()])
+}
+
+// Check adds a Conflict to e for every pair of cells in a row holding the
+// same value.
+func (c RowConstraint) Check(b *Board, e *ValidationError) {
+	checkGroups(b, "row", c.groups(b), e)
+}
+
+func (RowConstraint) groups(b *Board) [][]int {
+	l := b.layout()
+	return l.units[:l.size]
+}
+
+// ColumnConstraint is the rule that no column holds a value twice.
+type ColumnConstraint struct{}
+
+// Forbidden returns the values in the column of cell i.
+func (ColumnConstraint) Forbidden(b *Board, i int) uint32 {
+	size := b.Size()
+	return valuesIn(b, b.layout().units[size+i%size])
+}
+
+// Check adds a Conflict to e for every pair of cells in a column holding the
+// same value.
+func (c ColumnConstraint) Check(b *Board, e *ValidationError) {
+	checkGroups(b, "column", c.groups(b), e)
+}
+
+func (ColumnConstraint) groups(b *Board) [][]int {
+	l := b.layout()
+	return l.units[l.size : 2*l.size]
+}
+
+// BoxConstraint is the rule that no box, or region of a jigsaw board, holds
+// a value twice.
+type BoxConstraint struct{}
+
+// Forbidden returns the values in the box of cell i.
+func (BoxConstraint) Forbidden(b *Board, i int) uint32 {
+	l := b.layout()
+	return valuesIn(b, l.units[l.unitsOf[i][2]])
+}
+
+// Check adds a Conflict to e for every pair of cells in a box holding the
+// same value.
+func (c BoxConstraint) Check(b *Board, e *ValidationError) {
+	checkGroups(b, b.layout().boxName, c.groups(b), e)
+}
+
+func (BoxConstraint) groups(b *Board) [][]int {
+	l := b.layout()
+	return l.units[2*l.size:]
+}
+
+// constraints returns every rule the board's values must follow.
+func (b *Board) constraints() []Constraint {
+	constraints := []Constraint{RowConstraint{}, ColumnConstraint{}, BoxConstraint{}}
+	if len(b.Cages) > 0 {
+		constraints = append(constraints, newCageConstraint(b.layout(), b.Cages))
+	}
+	return append(constraints, b.Constraints...)
+}
+
+// valuesIn returns the values in the cells, with value v in bit v-1.
+func valuesIn(b *Board, cells []int) uint32 {
+	var values uint32
+	for _, i := range cells {
+		if value := b.Cells[i]; value > 0 && value <= MaxBoardSize {
+			values |= 1 << uint(value-1)
+		}
+	}
+	return values
+}
+
+// checkGroups adds a Conflict to e for every pair of cells in the same group
+// holding the same value. unit names the kind of group.
+func checkGroups(b *Board, unit string, groups [][]int, e *ValidationError) {
+	size := b.Size()
+	for g, cells := range groups {
+		for x := 0; x < len(cells); x++ {
+			value := b.Cells[cells[x]]
+			if value < 1 || value > size {
+				continue
+			}
+			for y := x + 1; y < len(cells); y++ {
+				if b.Cells[cells[y]] == value {
+					e.Conflicts = append(e.Conflicts, Conflict{
+						Unit:      unit,
+						UnitIndex: g,
+						Cells:     [2]int{cells[x], cells[y]},
+						Digit:     value,
+					})
+				}
+			}
+		}
+	}
+}
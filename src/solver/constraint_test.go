@@ -0,0 +1,87 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// evenCells is the rule that the cells hold even values.
+type evenCells []int
+
+func (c evenCells) Forbidden(b *Board, i int) uint32 {
+	for _, cell := range c {
+		if cell == i {
+			// the odd values
+			return 0x55555555
+		}
+	}
+	return 0
+}
+
+func (c evenCells) Check(b *Board, e *ValidationError) {
+	for _, i := range c {
+		if value := b.Cells[i]; value%2 == 1 {
+			e.Violations = append(e.Violations, Violation{
+				Rule:  "even",
+				Cells: []int{i},
+				Msg:   fmt.Sprintf("%d at %s is odd", value, cellNameOn(i, b.Size())),
+			})
+		}
+	}
+}
+
+func TestStandardConstraints(t *testing.T) {
+	b, _ := ParseBoard(testPuzzle, 0, 0)
+	// r1c1 sees 9 and 6 in its row, 8 and 4 in its column and 9, 4 and 5 in
+	// its box
+	expected := map[Constraint]uint32{
+		RowConstraint{}:    0x120,
+		ColumnConstraint{}: 0x88,
+		BoxConstraint{}:    0x118,
+	}
+	for c, values := range expected {
+		if forbidden := c.Forbidden(b, 0); forbidden != values {
+			t.Errorf("%T: expected %x - got %x instead", c, values, forbidden)
+		}
+	}
+}
+
+func TestExtraConstraint(t *testing.T) {
+	b, _ := NewBoard(2, 2)
+	even := evenCells{0, 5, 10, 15}
+	b.Constraints = []Constraint{even}
+	if err := b.Solve(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, i := range even {
+		if b.Cells[i]%2 != 0 {
+			t.Errorf("expected an even value at %s - got %s", cellNameOn(i, 4), b)
+		}
+	}
+	if _, err := b.Grid(); err == nil {
+		t.Error("expected an error converting a board with constraints to a Grid")
+	}
+	if clone := b.Clone(); len(clone.Constraints) != 1 {
+		t.Errorf("expected the clone to keep its constraint - got %v", clone.Constraints)
+	}
+
+	b, _ = NewBoard(2, 2)
+	b.Constraints = []Constraint{even}
+	b.Cells[5] = 3
+	err := b.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError - got %v", err)
+	}
+	if len(verr.Violations) != 1 || !strings.Contains(err.Error(), "3 at r2c2 is odd") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if cells := verr.Cells(); len(cells) != 1 || cells[0] != 5 {
+		t.Errorf("expected cell 5 to be involved - got %v", cells)
+	}
+	if count, err := b.CountSolutions(context.Background(), 0); err == nil || count != 0 {
+		t.Errorf("expected no solutions and an error - got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,48 @@
+package solver
+
+// CountSolutions returns the number of solutions to the puzzle, stopping once
+// limit solutions have been found. A limit of 0 or less counts every solution.
+func (grid Grid) CountSolutions(limit int) int {
+	count := 0
+	grid.EachSolution(func(Grid) bool {
+		count++
+		return limit <= 0 || count < limit
+	})
+	return count
+}
+
+// HasUniqueSolution returns true if the puzzle has exactly one solution.
+func (grid Grid) HasUniqueSolution() bool {
+	return grid.CountSolutions(2) == 1
+}
+
+// EachSolution calls fn with every solution to the puzzle in turn, stopping
+// early if fn returns false.
+func (grid Grid) EachSolution(fn func(solution Grid) bool) {
+	if grid.Validate() != nil {
+		return
+	}
+	s := newSearch(&grid, nil, Options{Order: MinimumRemaining, Propagate: true})
+	s.run(func() bool {
+		return fn(grid)
+	})
+}
+
+// Solutions returns a channel which receives every solution to the puzzle in
+// turn and is closed once there are no more. Closing done stops the search
+// early.
+func (grid Grid) Solutions(done <-chan struct{}) <-chan Grid {
+	ch := make(chan Grid)
+	go func() {
+		defer close(ch)
+		grid.EachSolution(func(solution Grid) bool {
+			select {
+			case ch <- solution:
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}()
+	return ch
+}
@@ -0,0 +1,81 @@
+package solver
+
+import "testing"
+
+func TestCountSolutions(t *testing.T) {
+	tables := []struct {
+		puzzle string
+		limit  int
+		count  int
+	}{
+		{testPuzzle, 0, 1},
+		{hardPuzzles[0], 10, 1},
+		// two 1s in the first row
+		{"110000000000000000000000000000000000000000000000000000000000000000000000000000000", 10, 0},
+		// the empty grid has far too many solutions to count
+		{"000000000000000000000000000000000000000000000000000000000000000000000000000000000", 100, 100},
+		// testPuzzle without the 4 at the start of the seventh row
+		{"009060000040010000050700320890400070000507000002009180000000002005000760060200400", 0, 3},
+		{"009060000040010000050700320890400070000507000002009180000000002005000760060200400", 2, 2},
+		// a solved grid
+		{"729365841346812957158794326893421675614587293572639184481976532235148769967253418", 0, 1},
+	}
+	for _, table := range tables {
+		grid, _ := NewGridFromString(table.puzzle)
+		count := grid.CountSolutions(table.limit)
+		if count != table.count {
+			t.Errorf("expected %d solutions for %s with limit %d - got %d instead", table.count, table.puzzle, table.limit, count)
+		}
+	}
+}
+
+func TestHasUniqueSolution(t *testing.T) {
+	grid, _ := NewGridFromString(testPuzzle)
+	if !grid.HasUniqueSolution() {
+		t.Errorf("expected %s to have a unique solution", testPuzzle)
+	}
+	grid[54] = 0
+	if grid.HasUniqueSolution() {
+		t.Errorf("expected %s to have multiple solutions", grid)
+	}
+}
+
+func TestEachSolution(t *testing.T) {
+	grid, _ := NewGridFromString(testPuzzle)
+	grid[54] = 0
+	seen := map[Grid]bool{}
+	grid.EachSolution(func(solution Grid) bool {
+		if seen[solution] {
+			t.Errorf("solution %s returned twice", solution)
+		}
+		seen[solution] = true
+		if solution.nextEmptyCellFromIndex(0) != -1 {
+			t.Errorf("solution %s has empty cells", solution)
+		}
+		for i, value := range grid {
+			if value != 0 && solution[i] != value {
+				t.Errorf("solution %s does not keep the given at %d", solution, i)
+			}
+		}
+		return true
+	})
+	if len(seen) != 3 {
+		t.Errorf("expected 3 solutions - got %d instead", len(seen))
+	}
+}
+
+func TestSolutionsCancel(t *testing.T) {
+	var grid Grid
+	done := make(chan struct{})
+	ch := grid.Solutions(done)
+	for i := 0; i < 5; i++ {
+		if _, ok := <-ch; !ok {
+			t.Fatalf("channel closed after %d solutions", i)
+		}
+	}
+	close(done)
+	// the search should stop and close the channel - drain anything which
+	// was already on its way
+	for range ch {
+	}
+}
@@ -0,0 +1,218 @@
+package solver
+
+import "context"
+
+// The exact cover matrix has a column for every constraint - each cell holds a
+// digit, and each row, column and box holds every digit once - and a row for
+// every digit in every cell.
+const (
+	dlxCellColumns   = 0
+	dlxRowColumns    = 81
+	dlxColumnColumns = 162
+	dlxBoxColumns    = 243
+	dlxColumns       = 324
+	dlxRows          = 729
+	dlxRoot          = 0
+)
+
+// DancingLinks solves grids by encoding them as an exact cover problem and
+// solving that with Knuth's Algorithm X.
+type DancingLinks struct{}
+
+// Solve fills in grid, sending every change to ch if it is not nil. The grid
+// is left as it was if no solution is found.
+func (DancingLinks) Solve(ctx context.Context, grid *Grid, ch chan UpdateEvent) error {
+	if err := grid.Validate(); err != nil {
+		return err
+	}
+	d := newDLX(grid, ch)
+	d.canceller = newCanceller(ctx)
+	if !d.coverGivens() {
+		return ErrUnsolvable
+	}
+	start := *grid
+	if d.search() {
+		return nil
+	}
+	*grid = start
+	return solveError(ctx, d.stopped)
+}
+
+// dlx is a sparse exact cover matrix stored as circular doubly linked lists.
+// Node 0 is the root, nodes 1 to dlxColumns are the column headers and the
+// rest are the 1s in the matrix. Links are indexes into the slices.
+type dlx struct {
+	canceller
+
+	grid *Grid
+	ch   chan UpdateEvent
+
+	left, right, up, down []int
+	column                []int // column header of each node
+	row                   []int // matrix row of each node
+	size                  []int // number of nodes in each column
+
+	// first node of each matrix row
+	rowStart [dlxRows]int
+}
+
+func newDLX(grid *Grid, ch chan UpdateEvent) *dlx {
+	nodes := 1 + dlxColumns + dlxRows*4
+	d := &dlx{
+		grid:   grid,
+		ch:     ch,
+		left:   make([]int, nodes),
+		right:  make([]int, nodes),
+		up:     make([]int, nodes),
+		down:   make([]int, nodes),
+		column: make([]int, nodes),
+		row:    make([]int, nodes),
+		size:   make([]int, dlxColumns+1),
+	}
+
+	for c := 0; c <= dlxColumns; c++ {
+		d.left[c] = c - 1
+		d.right[c] = c + 1
+		d.up[c] = c
+		d.down[c] = c
+		d.column[c] = c
+	}
+	d.left[dlxRoot] = dlxColumns
+	d.right[dlxColumns] = dlxRoot
+
+	n := dlxColumns + 1
+	for r := 0; r < dlxRows; r++ {
+		index, digit := r/9, r%9
+		columns := [4]int{
+			dlxCellColumns + index,
+			dlxRowColumns + rowOf[index]*9 + digit,
+			dlxColumnColumns + columnOf[index]*9 + digit,
+			dlxBoxColumns + boxOf[index]*9 + digit,
+		}
+		d.rowStart[r] = n
+		for k, c := range columns {
+			// column headers are offset by the root
+			c++
+			d.column[n] = c
+			d.row[n] = r
+			d.left[n] = n - 1
+			d.right[n] = n + 1
+			if k == 0 {
+				d.left[n] = n + 3
+			} else if k == 3 {
+				d.right[n] = n - 3
+			}
+			d.up[n] = d.up[c]
+			d.down[n] = c
+			d.down[d.up[c]] = n
+			d.up[c] = n
+			d.size[c]++
+			n++
+		}
+	}
+	return d
+}
+
+func (d *dlx) cover(c int) {
+	d.right[d.left[c]] = d.right[c]
+	d.left[d.right[c]] = d.left[c]
+	for i := d.down[c]; i != c; i = d.down[i] {
+		for j := d.right[i]; j != i; j = d.right[j] {
+			d.down[d.up[j]] = d.down[j]
+			d.up[d.down[j]] = d.up[j]
+			d.size[d.column[j]]--
+		}
+	}
+}
+
+func (d *dlx) uncover(c int) {
+	for i := d.up[c]; i != c; i = d.up[i] {
+		for j := d.left[i]; j != i; j = d.left[j] {
+			d.size[d.column[j]]++
+			d.down[d.up[j]] = j
+			d.up[d.down[j]] = j
+		}
+	}
+	d.right[d.left[c]] = c
+	d.left[d.right[c]] = c
+}
+
+// coverGivens removes the constraints satisfied by the digits already in the
+// grid. Returns false if the givens conflict with each other.
+func (d *dlx) coverGivens() bool {
+	for index, value := range d.grid {
+		if value == 0 {
+			continue
+		}
+		if value < 1 || value > 9 {
+			return false
+		}
+		start := d.rowStart[index*9+value-1]
+		j := start
+		for {
+			c := d.column[j]
+			if d.right[d.left[c]] != c {
+				// already covered by another given
+				return false
+			}
+			d.cover(c)
+			j = d.right[j]
+			if j == start {
+				break
+			}
+		}
+	}
+	return true
+}
+
+// set records the digit for a matrix row in the grid.
+func (d *dlx) set(r int, selected bool) {
+	index, value := r/9, 0
+	if selected {
+		value = r%9 + 1
+	}
+	d.grid[index] = value
+	if d.ch != nil {
+		d.send(d.ch, UpdateEvent{Index: index, Value: value})
+	}
+}
+
+func (d *dlx) search() bool {
+	if d.stop() {
+		return false
+	}
+	if d.right[dlxRoot] == dlxRoot {
+		return true
+	}
+
+	// branch on the column with the fewest rows
+	c := d.right[dlxRoot]
+	for j := d.right[c]; j != dlxRoot; j = d.right[j] {
+		if d.size[j] < d.size[c] {
+			c = j
+		}
+	}
+	if d.size[c] == 0 {
+		return false
+	}
+
+	d.cover(c)
+	for r := d.down[c]; r != c; r = d.down[r] {
+		d.set(d.row[r], true)
+		for j := d.right[r]; j != r; j = d.right[j] {
+			d.cover(d.column[j])
+		}
+		if d.search() {
+			return true
+		}
+		if d.stopped {
+			return false
+		}
+		for j := d.left[r]; j != r; j = d.left[j] {
+			d.uncover(d.column[j])
+		}
+		d.set(d.row[r], false)
+	}
+	d.uncover(c)
+	return false
+}
@@ -0,0 +1,205 @@
+package solver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Candidate is a digit in a cell.
+type Candidate struct {
+	Index int `json:"index"`
+	Digit int `json:"digit"`
+}
+
+func (c Candidate) String() string {
+	return fmt.Sprintf("%s=%d", cellName(c.Index), c.Digit)
+}
+
+// Step is a single deduction made by the logical solver.
+type Step struct {
+	// Technique is the name of the solving technique used.
+	Technique string `json:"technique"`
+	// Cells are the cells forming the pattern the deduction is based on.
+	Cells []int `json:"cells"`
+	// Placements are the digits the step fills in.
+	Placements []Candidate `json:"placements,omitempty"`
+	// Eliminations are the candidates the step rules out.
+	Eliminations []Candidate `json:"eliminations,omitempty"`
+	// Description explains the step in plain English.
+	Description string `json:"description"`
+
+	// the kind of unit the pattern was found in, if it matters
+	unit unitKind
+}
+
+// Explanation is a step by step logical solution to a puzzle.
+type Explanation struct {
+	Steps []Step `json:"steps"`
+	// Solved is false if the techniques ran out before the puzzle was
+	// solved - the steps then only go as far as logic could take them.
+	Solved bool `json:"solved"`
+	// Result is the grid after the last step.
+	Result Grid `json:"result"`
+}
+
+// Explain solves the puzzle the way a person would, using named techniques,
+// and returns every step taken. Returns the error from Validate if the grid
+// is invalid.
+func (grid Grid) Explain() (Explanation, error) {
+	return grid.explain(explainTechniques)
+}
+
+func (grid Grid) explain(techniques []technique) (Explanation, error) {
+	if err := grid.Validate(); err != nil {
+		return Explanation{}, err
+	}
+	p := newPencilmarks(grid)
+	var steps []Step
+	for !p.solved() {
+		if !p.consistent() {
+			break
+		}
+		step, ok := p.nextStep(techniques)
+		if !ok {
+			break
+		}
+		p.apply(step)
+		steps = append(steps, step)
+	}
+	return Explanation{Steps: steps, Solved: p.solved(), Result: p.grid}, nil
+}
+
+// pencilmarks is a grid annotated with the candidates for every empty cell.
+type pencilmarks struct {
+	grid       Grid
+	candidates [81]digitMask
+}
+
+func newPencilmarks(grid Grid) *pencilmarks {
+	p := &pencilmarks{grid: grid}
+	o := newOccupancy(&grid)
+	for i, value := range grid {
+		if value == 0 {
+			p.candidates[i] = o.candidates(i)
+		}
+	}
+	return p
+}
+
+func (p *pencilmarks) solved() bool {
+	for _, value := range p.grid {
+		if value == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// consistent returns false if an empty cell has run out of candidates.
+func (p *pencilmarks) consistent() bool {
+	for i, value := range p.grid {
+		if value == 0 && p.candidates[i] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *pencilmarks) nextStep(techniques []technique) (Step, bool) {
+	for _, t := range techniques {
+		if step, ok := t.find(p); ok {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
+func (p *pencilmarks) apply(step Step) {
+	for _, c := range step.Placements {
+		p.place(c.Index, c.Digit)
+	}
+	for _, c := range step.Eliminations {
+		p.candidates[c.Index] &^= maskOf(c.Digit)
+	}
+}
+
+func (p *pencilmarks) place(index, digit int) {
+	p.grid[index] = digit
+	p.candidates[index] = 0
+	m := maskOf(digit)
+	for _, peer := range peers[index] {
+		p.candidates[peer] &^= m
+	}
+}
+
+// cellsWith returns the cells in a unit which have digit as a candidate.
+func (p *pencilmarks) cellsWith(u, digit int) []int {
+	var cells []int
+	m := maskOf(digit)
+	for _, i := range units[u] {
+		if p.candidates[i]&m != 0 {
+			cells = append(cells, i)
+		}
+	}
+	return cells
+}
+
+// unitKind is the kind of a unit - a row, column or box.
+type unitKind int
+
+const (
+	noUnit unitKind = iota
+	rowUnit
+	columnUnit
+	boxUnit
+)
+
+func kindOf(u int) unitKind {
+	return unitKind(u/9 + 1)
+}
+
+func unitName(u int) string {
+	return fmt.Sprintf("%s %d", unitNames[u/9], u%9+1)
+}
+
+func cellNames(cells []int) string {
+	names := make([]string, len(cells))
+	for i, c := range cells {
+		names[i] = cellName(c)
+	}
+	return joinWords(names)
+}
+
+func digitNames(m digitMask) string {
+	var names []string
+	for _, d := range m.digits() {
+		names = append(names, fmt.Sprint(d))
+	}
+	return joinWords(names)
+}
+
+// joinWords joins words into an English list - "a, b and c".
+func joinWords(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+// eliminationNames describes a list of eliminations - "4 from r1c2 and r1c7,
+// 8 from r3c3".
+func eliminationNames(eliminations []Candidate) string {
+	var digits []int
+	cells := map[int][]int{}
+	for _, c := range eliminations {
+		if _, ok := cells[c.Digit]; !ok {
+			digits = append(digits, c.Digit)
+		}
+		cells[c.Digit] = append(cells[c.Digit], c.Index)
+	}
+	var parts []string
+	for _, d := range digits {
+		parts = append(parts, fmt.Sprintf("%d from %s", d, cellNames(cells[d])))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,102 @@
+package solver
+
+import "testing"
+
+// checkSteps makes sure that every step in an explanation agrees with the
+// solution to the puzzle.
+func checkSteps(t *testing.T, puzzle string, steps []Step) {
+	grid, _ := NewGridFromString(puzzle)
+	solution := grid
+	if !solution.Solve(nil) {
+		t.Fatalf("could not solve %s", puzzle)
+	}
+	for n, step := range steps {
+		for _, c := range step.Placements {
+			if solution[c.Index] != c.Digit {
+				t.Errorf("%s step %d (%s) places %v but the solution has %d", puzzle, n, step.Technique, c, solution[c.Index])
+			}
+		}
+		for _, c := range step.Eliminations {
+			if solution[c.Index] == c.Digit {
+				t.Errorf("%s step %d (%s) eliminates %v which is in the solution", puzzle, n, step.Technique, c)
+			}
+		}
+		if len(step.Placements) == 0 && len(step.Eliminations) == 0 {
+			t.Errorf("%s step %d (%s) does nothing", puzzle, n, step.Technique)
+		}
+		if step.Description == "" {
+			t.Errorf("%s step %d (%s) has no description", puzzle, n, step.Technique)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	for _, s := range append(append([]string{}, mixedPuzzles...), techniquePuzzles...) {
+		grid, _ := NewGridFromString(s)
+		explanation, err := grid.Explain()
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", s, err)
+		}
+		checkSteps(t, s, explanation.Steps)
+	}
+}
+
+func TestExplainSolvesEasyPuzzles(t *testing.T) {
+	for _, s := range easyPuzzles {
+		grid, _ := NewGridFromString(s)
+		explanation, _ := grid.Explain()
+		if !explanation.Solved {
+			t.Errorf("expected %s to be solved", s)
+		}
+		for _, step := range explanation.Steps {
+			if step.Technique != NakedSingle && step.Technique != HiddenSingle {
+				t.Errorf("expected %s to be solved with singles - used %s", s, step.Technique)
+			}
+		}
+		solution := grid
+		solution.Solve(nil)
+		if explanation.Result != solution {
+			t.Errorf("expected %s to be solved as %s - got %s instead", s, solution, explanation.Result)
+		}
+	}
+}
+
+func TestExplainTechniques(t *testing.T) {
+	used := map[string]bool{}
+	for _, s := range techniquePuzzles {
+		grid, _ := NewGridFromString(s)
+		explanation, _ := grid.Explain()
+		for _, step := range explanation.Steps {
+			used[step.Technique] = true
+		}
+	}
+	for _, technique := range explainTechniques {
+		if !used[technique.name] {
+			t.Errorf("no test puzzle uses %s", technique.name)
+		}
+	}
+}
+
+func TestExplainInvalid(t *testing.T) {
+	grid := Grid{1, 1}
+	if _, err := grid.Explain(); err == nil {
+		t.Error("expected an error for an invalid grid")
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	var got [][]int
+	combinations(4, 2, func(chosen []int) bool {
+		got = append(got, append([]int{}, chosen...))
+		return true
+	})
+	expected := [][]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v - got %v instead", expected, got)
+	}
+	for i := range expected {
+		if got[i][0] != expected[i][0] || got[i][1] != expected[i][1] {
+			t.Fatalf("expected %v - got %v instead", expected, got)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// how many puzzles GenerateWithOptions tries by default before giving up
+const defaultGenerateAttempts = 1000
+
+// Generator produces random puzzles with a unique solution. It is not safe
+// for concurrent use.
+type Generator struct {
+	rand *rand.Rand
+}
+
+// NewGenerator returns a Generator seeded with seed. Generators with the same
+// seed produce the same puzzles.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{rand: rand.New(rand.NewSource(seed))}
+}
+
+// GenerateOptions describes the puzzles GenerateWithOptions should produce.
+// The zero value accepts any puzzle.
+type GenerateOptions struct {
+	// Difficulties lists the acceptable difficulties - any difficulty is
+	// acceptable if it is empty.
+	Difficulties []Difficulty
+	// MinClues and MaxClues limit the number of givens if they are greater
+	// than 0.
+	MinClues int
+	MaxClues int
+	// Symmetry is the pattern the givens follow.
+	Symmetry Symmetry
+	// MaxAttempts is the number of puzzles to try before giving up. It
+	// defaults to 1000.
+	MaxAttempts int
+	// Timeout is how long to keep trying for if it is greater than 0.
+	Timeout time.Duration
+}
+
+func (options GenerateOptions) accepts(grid Grid) bool {
+	clues := 0
+	for _, value := range grid {
+		if value != 0 {
+			clues++
+		}
+	}
+	if clues < options.MinClues || (options.MaxClues > 0 && clues > options.MaxClues) {
+		return false
+	}
+	if len(options.Difficulties) == 0 {
+		return true
+	}
+	rating, err := grid.Rate()
+	if err != nil {
+		return false
+	}
+	for _, d := range options.Difficulties {
+		if rating.Difficulty == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate returns a random puzzle with a unique solution. Givens are removed
+// from a random solved grid in random order for as long as the solution stays
+// unique, so no given can be removed from the result.
+func (g *Generator) Generate() Grid {
+	return g.attempt(GenerateOptions{})
+}
+
+// GenerateWithOptions returns a random puzzle with a unique solution which
+// meets the options. Returns an error if it can't find one within the
+// attempts or time allowed.
+func (g *Generator) GenerateWithOptions(options GenerateOptions) (Grid, error) {
+	return g.GenerateContext(context.Background(), options)
+}
+
+// GenerateContext is GenerateWithOptions which also gives up, returning
+// ctx.Err(), once ctx is done. ctx is checked between attempts.
+func (g *Generator) GenerateContext(ctx context.Context, options GenerateOptions) (Grid, error) {
+	attempts := options.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultGenerateAttempts
+	}
+	var deadline time.Time
+	if options.Timeout > 0 {
+		deadline = time.Now().Add(options.Timeout)
+	}
+	for n := 1; n <= attempts; n++ {
+		if err := ctx.Err(); err != nil {
+			return Grid{}, err
+		}
+		grid := g.attempt(options)
+		if options.accepts(grid) {
+			return grid, nil
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return Grid{}, fmt.Errorf("could not generate a matching puzzle within %v (%d attempts)", options.Timeout, n)
+		}
+	}
+	return Grid{}, fmt.Errorf("could not generate a matching puzzle in %d attempts", attempts)
+}
+
+// attempt removes givens from a random solved grid, one symmetric group of
+// cells at a time, for as long as the solution stays unique and at least
+// MinClues givens are left.
+func (g *Generator) attempt(options GenerateOptions) Grid {
+	grid := g.Solution()
+	clues := 81
+	orbits := options.Symmetry.orbits()
+	for _, o := range g.rand.Perm(len(orbits)) {
+		orbit := orbits[o]
+		if clues-len(orbit) < options.MinClues {
+			continue
+		}
+		removed := grid
+		for _, i := range orbit {
+			removed[i] = 0
+		}
+		if removed.HasUniqueSolution() {
+			grid = removed
+			clues -= len(orbit)
+		}
+	}
+	return grid
+}
+
+// Solution returns a random solved grid.
+func (g *Generator) Solution() Grid {
+	var grid Grid
+	o := newOccupancy(&grid)
+	g.fill(&grid, &o)
+	return grid
+}
+
+// fill fills in the empty cells of grid with random digits, always picking
+// the cell with the fewest candidates next. Returns false if it gets stuck.
+func (g *Generator) fill(grid *Grid, o *occupancy) bool {
+	index, candidates := grid.nextCell(o, MinimumRemaining, 0)
+	if index == -1 {
+		return true
+	}
+	digits := candidates.digits()
+	g.rand.Shuffle(len(digits), func(i, j int) {
+		digits[i], digits[j] = digits[j], digits[i]
+	})
+	for _, digit := range digits {
+		grid[index] = digit
+		o.place(index, digit)
+		if g.fill(grid, o) {
+			return true
+		}
+		o.remove(index, digit)
+		grid[index] = 0
+	}
+	return false
+}
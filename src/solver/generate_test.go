@@ -0,0 +1,121 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	g := NewGenerator(1)
+	for n := 0; n < 5; n++ {
+		grid := g.Generate()
+		if !grid.HasUniqueSolution() {
+			t.Fatalf("generated puzzle %s does not have a unique solution", grid)
+		}
+		// no given can be removed without losing uniqueness
+		for i, value := range grid {
+			if value == 0 {
+				continue
+			}
+			grid[i] = 0
+			if grid.HasUniqueSolution() {
+				t.Errorf("given at %s of %s can be removed", cellName(i), grid)
+			}
+			grid[i] = value
+		}
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	a := NewGenerator(42).Generate()
+	b := NewGenerator(42).Generate()
+	if a != b {
+		t.Errorf("expected the same seed to produce the same puzzle - got %s and %s", a, b)
+	}
+}
+
+func TestSolution(t *testing.T) {
+	solution := NewGenerator(7).Solution()
+	if err := solution.Validate(); err != nil {
+		t.Fatalf("invalid solution %s: %v", solution, err)
+	}
+	if solution.nextEmptyCellFromIndex(0) != -1 {
+		t.Errorf("solution %s has empty cells", solution)
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	g := NewGenerator(1)
+	for n := 0; n < b.N; n++ {
+		g.Generate()
+	}
+}
+
+func TestGenerateWithOptions(t *testing.T) {
+	g := NewGenerator(3)
+	tables := []GenerateOptions{
+		{Symmetry: Rotational180},
+		{Symmetry: Mirror},
+		{Symmetry: Diagonal},
+		{Symmetry: Rotational90},
+		{MinClues: 30, MaxClues: 32},
+		{Difficulties: []Difficulty{Hard}, MinClues: 24, MaxClues: 28, Symmetry: Rotational180},
+	}
+	for _, options := range tables {
+		grid, err := g.GenerateWithOptions(options)
+		if err != nil {
+			t.Errorf("%+v: unexpected error %v", options, err)
+			continue
+		}
+		if !grid.HasUniqueSolution() {
+			t.Errorf("%+v: %s does not have a unique solution", options, grid)
+		}
+		if !options.accepts(grid) {
+			t.Errorf("%+v: %s does not meet the options", options, grid)
+		}
+		for i := range grid {
+			if (grid[i] == 0) != (grid[options.Symmetry.image(i)] == 0) {
+				t.Errorf("%+v: %s is not symmetric at %s", options, grid, cellName(i))
+				break
+			}
+		}
+	}
+}
+
+func TestGenerateWithOptionsGivesUp(t *testing.T) {
+	g := NewGenerator(1)
+	// no puzzle has a unique solution with so few clues
+	if _, err := g.GenerateWithOptions(GenerateOptions{MaxClues: 10, MaxAttempts: 3}); err == nil {
+		t.Error("expected an error for impossible options")
+	}
+}
+
+func TestGenerateContextStops(t *testing.T) {
+	g := NewGenerator(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	options := GenerateOptions{MaxClues: 10, MaxAttempts: 1 << 30}
+	if _, err := g.GenerateContext(ctx, options); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestSymmetryOrbits(t *testing.T) {
+	sizes := map[Symmetry]int{
+		NoSymmetry:    81,
+		Rotational180: 41,
+		Mirror:        45,
+		Diagonal:      45,
+		Rotational90:  21,
+	}
+	for symmetry, size := range sizes {
+		if n := len(symmetry.orbits()); n != size {
+			t.Errorf("expected %v to have %d orbits - got %d instead", symmetry, size, n)
+		}
+		parsed, err := ParseSymmetry(symmetry.String())
+		if err != nil || parsed != symmetry {
+			t.Errorf("could not parse %v: %v", symmetry, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package solver
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"strconv"
@@ -38,10 +39,14 @@ func (grid Grid) Print(w io.Writer) {
 	}
 }
 
-// NewGridFromString returns a Grid object from an 81 character string.
+// NewGridFromString returns a Grid object from an 81 character string. Use
+// ParseGrid to accept other formats.
 func NewGridFromString(s string) (Grid, error) {
 	grid := Grid{}
 	r := []rune(s)
+	if len(r) != 81 {
+		return grid, fmt.Errorf("expected 81 characters - got %d instead", len(r))
+	}
 	for i := 0; i < 81; i++ {
 		value, err := strconv.Atoi(string(r[i]))
 		if err != nil {
@@ -60,46 +65,42 @@ func (grid Grid) String() string {
 	return b.String()
 }
 
+// Options configures the backtracking solver.
+type Options struct {
+	// Order determines which empty cell is filled next.
+	Order CellOrder
+
+	// Propagate fills in naked and hidden singles after every placement,
+	// before the solver has to guess.
+	Propagate bool
+}
+
 // Solve will keep running till it finds a solution to the puzzle. Returns true if successful, false if there is a problem.
 func (grid *Grid) Solve(ch chan UpdateEvent) bool {
-	index := grid.nextEmptyCellFromIndex(0)
-	if index == -1 {
-		return true
-	}
-
-	s := newStack()
-	s.push(newCellContext(index, grid.nextEmptyCellFromIndex(index+1), grid.candidatesForCell(index)))
-	var context *cellcontext
-	var updateEvent UpdateEvent
+	return grid.SolveWithOptions(ch, Options{})
+}
 
-	for s.hasMore() {
-		context, _ = s.peek()
-		if context.hasMoreCandidates() {
-			candidate := context.nextCandidate()
-			grid[context.index] = candidate
-			updateEvent.Index = context.index
-			updateEvent.Value = candidate
-			if ch != nil {
-				ch <- updateEvent
-			}
-			if context.nextEmpty == -1 {
-				s.pop()
-				return true
-			}
-			s.push(newCellContext(context.nextEmpty, grid.nextEmptyCellFromIndex(context.nextEmpty+1), grid.candidatesForCell(context.nextEmpty)))
-		} else {
-			// unsuccessful - so we'll reset the cell to empty
-			grid[context.index] = 0
-			updateEvent.Index = context.index
-			updateEvent.Value = 0
-			if ch != nil {
-				ch <- updateEvent
-			}
-			s.pop()
-		}
-	}
+// SolveWithOptions is like Solve but lets the caller configure the search.
+func (grid *Grid) SolveWithOptions(ch chan UpdateEvent, options Options) bool {
+	s := newSearch(grid, ch, options)
+	return s.solve()
+}
 
-	return false
+// SolveContext is like SolveWithOptions but stops searching once ctx is done.
+// Returns the error from Validate if the grid is invalid, ErrUnsolvable if the
+// puzzle has no solution, or ErrCancelled or ErrTimedOut if the search was
+// stopped before it found one. The grid is left as it was if the search does
+// not succeed.
+func (grid *Grid) SolveContext(ctx context.Context, ch chan UpdateEvent, options Options) error {
+	if err := grid.Validate(); err != nil {
+		return err
+	}
+	s := newSearch(grid, ch, options)
+	s.canceller = newCanceller(ctx)
+	if s.solve() {
+		return nil
+	}
+	return solveError(ctx, s.stopped)
 }
 
 // Clone produces a copy of grid.
@@ -118,101 +119,17 @@ func (grid Grid) nextEmptyCellFromIndex(index int) int {
 	return -1
 }
 
+// candidatesForCell computes the candidates for a cell by scanning its row,
+// column and box, including the cell itself. Solve uses the occupancy masks
+// instead - this is kept as a reference implementation for tests and
+// benchmarks.
 func (grid Grid) candidatesForCell(index int) []int {
 	if index > 80 {
 		return []int{}
 	}
-	return intersectingCandidates(
-		candidatesFromDigits(grid.digitsInRow(index)),
-		candidatesFromDigits(grid.digitsInColumn(index)),
-		candidatesFromDigits(grid.digitsInBox(index)))
-}
-
-func (grid Grid) digitsInRow(index int) []int {
-	var digits []int
-	var value int
-	y := index / 9
-	for i := y * 9; i < (y+1)*9; i++ {
-		value = grid[i]
-		if value != 0 {
-			digits = append(digits, value)
-		}
-	}
-
-	return digits
-}
-
-func (grid Grid) digitsInColumn(index int) []int {
-	var digits []int
-	var value int
-	x := index % 9
-	for i := x; i < 81; i += 9 {
-		value = grid[i]
-		if value != 0 {
-			digits = append(digits, value)
-		}
+	taken := maskOf(grid[index])
+	for _, peer := range peers[index] {
+		taken |= maskOf(grid[peer])
 	}
-
-	return digits
-}
-
-func (grid Grid) digitsInBox(index int) []int {
-	var digits []int
-	var value int
-	row := index / 9
-	column := index % 9
-
-	// find index of top-left cell in box
-	i := (row/3)*27 + (column/3)*3
-
-	for y := 0; y < 3; y++ {
-		for x := 0; x < 3; x++ {
-			value = grid[i]
-			i++
-			if value != 0 {
-				digits = append(digits, value)
-			}
-		}
-		i += 6
-	}
-
-	return digits
-}
-
-// returns inverse of argument
-func candidatesFromDigits(digits []int) []int {
-	var taken [9]bool
-	var candidates []int
-	for _, i := range digits {
-		taken[i-1] = true
-	}
-	for i, v := range taken {
-		if !v {
-			candidates = append(candidates, i+1)
-		}
-	}
-
-	return candidates
-}
-
-func intersectingCandidates(x, y, z []int) []int {
-	var count [9]int
-	for _, value := range x {
-		count[value-1]++
-	}
-	for _, value := range y {
-		count[value-1]++
-	}
-	for _, value := range z {
-		count[value-1]++
-	}
-
-	var candidates []int
-	for i, v := range count {
-		if v == 3 {
-			candidates = append(candidates, i+1)
-		}
-	}
-
-	return candidates
+	return (allDigits &^ taken).digits()
 }
@@ -5,8 +5,10 @@ import (
 	"testing"
 )
 
+const testPuzzle = "009060000040010000050700320890400070000507000002009180400000002005000760060200400"
+
 func TestSolve(t *testing.T) {
-	s := "009060000040010000050700320890400070000507000002009180400000002005000760060200400"
+	s := testPuzzle
 	grid := Grid{}
 	r := []rune(s)
 	for i := 0; i < 81; i++ {
@@ -17,3 +19,142 @@ func TestSolve(t *testing.T) {
 		t.Error("did not manage to solve the puzzle")
 	}
 }
+
+func TestOccupancyCandidates(t *testing.T) {
+	for _, s := range append(hardPuzzles, testPuzzle) {
+		grid, err := NewGridFromString(s)
+		if err != nil {
+			t.Fatalf("could not parse %s: %v", s, err)
+		}
+		occupied := newOccupancy(&grid)
+		for i := 0; i < 81; i++ {
+			expected := grid.candidatesForCell(i)
+			got := occupied.candidates(i).digits()
+			if len(expected) != len(got) {
+				t.Fatalf("puzzle %s cell %d: expected candidates %v - got %v instead", s, i, expected, got)
+			}
+			for j := range expected {
+				if expected[j] != got[j] {
+					t.Fatalf("puzzle %s cell %d: expected candidates %v - got %v instead", s, i, expected, got)
+				}
+			}
+		}
+	}
+}
+
+func TestSolveHard(t *testing.T) {
+	for _, s := range hardPuzzles {
+		grid, _ := NewGridFromString(s)
+		if !grid.Solve(nil) {
+			t.Errorf("did not manage to solve %s", s)
+		}
+		if grid.nextEmptyCellFromIndex(0) != -1 {
+			t.Errorf("solution for %s still has empty cells", s)
+		}
+	}
+}
+
+func BenchmarkCandidatesScan(b *testing.B) {
+	grid, _ := NewGridFromString(testPuzzle)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < 81; i++ {
+			grid.candidatesForCell(i)
+		}
+	}
+}
+
+func BenchmarkCandidatesMask(b *testing.B) {
+	grid, _ := NewGridFromString(testPuzzle)
+	occupied := newOccupancy(&grid)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < 81; i++ {
+			occupied.candidates(i)
+		}
+	}
+}
+
+func BenchmarkSolve(b *testing.B) {
+	start, _ := NewGridFromString(testPuzzle)
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		grid := start
+		grid.Solve(nil)
+	}
+}
+
+func BenchmarkSolveHard(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for _, s := range hardPuzzles {
+			grid, _ := NewGridFromString(s)
+			grid.Solve(nil)
+		}
+	}
+}
+
+func TestSolveMinimumRemaining(t *testing.T) {
+	for _, s := range append(hardPuzzles, testPuzzle) {
+		grid, _ := NewGridFromString(s)
+		if !grid.SolveWithOptions(nil, Options{Order: MinimumRemaining}) {
+			t.Errorf("did not manage to solve %s", s)
+		}
+		if grid.nextEmptyCellFromIndex(0) != -1 {
+			t.Errorf("solution for %s still has empty cells", s)
+		}
+	}
+}
+
+func TestParseCellOrder(t *testing.T) {
+	tables := []struct {
+		name  string
+		order CellOrder
+		ok    bool
+	}{
+		{"", Sequential, true},
+		{"sequential", Sequential, true},
+		{"mrv", MinimumRemaining, true},
+		{"random", Sequential, false},
+	}
+	for _, table := range tables {
+		order, err := ParseCellOrder(table.name)
+		if (err == nil) != table.ok || order != table.order {
+			t.Errorf("ParseCellOrder(%q) returned %v, %v", table.name, order, err)
+		}
+	}
+}
+
+func BenchmarkSolveHardMinimumRemaining(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for _, s := range hardPuzzles {
+			grid, _ := NewGridFromString(s)
+			grid.SolveWithOptions(nil, Options{Order: MinimumRemaining})
+		}
+	}
+}
+
+func TestSolvePropagate(t *testing.T) {
+	for _, s := range append(hardPuzzles, testPuzzle) {
+		for _, order := range []CellOrder{Sequential, MinimumRemaining} {
+			grid, _ := NewGridFromString(s)
+			if !grid.SolveWithOptions(nil, Options{Order: order, Propagate: true}) {
+				t.Errorf("did not manage to solve %s with order %v", s, order)
+			}
+			if grid.nextEmptyCellFromIndex(0) != -1 {
+				t.Errorf("solution for %s with order %v still has empty cells", s, order)
+			}
+		}
+	}
+}
+
+func BenchmarkSolveHardPropagate(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for _, s := range hardPuzzles {
+			grid, _ := NewGridFromString(s)
+			grid.SolveWithOptions(nil, Options{Order: MinimumRemaining, Propagate: true})
+		}
+	}
+}
@@ -13,6 +13,7 @@ func StaticHTML(w io.Writer) {
 			<title>Sudoku Solver</title>
 			<style>
 				.grid {
+					position: relative;
 					display: grid;
 					grid-template-columns: repeat(3, 180px);
 					grid-template-rows: repeat(3, 180px);
@@ -43,34 +44,152 @@ func StaticHTML(w io.Writer) {
 				#keypad {
 					visibility: hidden;
 				}
+				#overlay {
+					position: absolute;
+					left: 0;
+					top: 0;
+					pointer-events: none;
+					overflow: visible;
+				}
+				.cageOutline {
+					stroke: rgba(0, 0, 0, 0.6);
+					stroke-width: 1;
+					stroke-dasharray: 4 3;
+				}
+				.extraRegion {
+					fill: rgba(100, 149, 237, 0.2);
+				}
+				.cageSum {
+					font-size: 11px;
+					fill: black;
+				}
+				.whiteDot {
+					fill: white;
+					stroke: black;
+					stroke-width: 1;
+				}
+				.blackDot {
+					fill: black;
+				}
+				.thermometer {
+					fill: none;
+					stroke: rgba(128, 128, 128, 0.4);
+					stroke-linecap: round;
+					stroke-linejoin: round;
+				}
+				.thermometerBulb {
+					fill: rgba(128, 128, 128, 0.4);
+				}
+				.arrow {
+					fill: none;
+					stroke: rgba(0, 0, 0, 0.5);
+					stroke-width: 2;
+				}
+				.sandwichClue {
+					font-size: 14px;
+					fill: black;
+				}
 				.highlighted {
 					background-color: lightgray;
 				}
+				.conflict {
+					background-color: lightcoral;
+				}
+				#board {
+					display: flex;
+				}
+				#walkthrough {
+					visibility: hidden;
+					width: 400px;
+					height: 540px;
+					overflow-y: auto;
+					padding-left: 20px;
+				}
+				.step {
+					cursor: pointer;
+					padding: 3px;
+				}
+				.currentStep {
+					background-color: lightgray;
+				}
+				.pattern {
+					background-color: lightyellow;
+				}
+				.placed {
+					background-color: lightgreen;
+				}
+				.eliminated {
+					background-color: mistyrose;
+				}
 			</style>
 		</head>
 		<body onload="initPage()">
 			<h1>Sudoku Solver</h1>
 			<div id="main">
 				<div style="padding: 10px;">
+					<select id="sizeSelect" onchange="sizeChanged()">
+						<option value="2x2">4&times;4</option>
+						<option value="2x3">6&times;6</option>
+						<option value="2x4">8&times;8</option>
+						<option value="3x3" selected>9&times;9</option>
+						<option value="3x4">12&times;12</option>
+						<option value="4x4">16&times;16</option>
+						<option value="5x5">25&times;25</option>
+					</select>
+					&nbsp;
+					<input id="layoutInput" type="text" size="12" placeholder="Jigsaw layout" onchange="layoutChanged()"/>
+					&nbsp;
+					<input id="cagesInput" type="text" size="12" placeholder="Killer cages (JSON)" onchange="cagesChanged()"/>
+					&nbsp;
+					<input id="kropkiInput" type="text" size="12" placeholder="Kropki dots (JSON)" onchange="kropkiChanged()"/>
+					&nbsp;
+					<input id="cluesInput" type="text" size="12" placeholder="Thermometers, arrows, sandwiches (JSON)" onchange="cluesChanged()"/>
+					&nbsp;
+					<input id="xCheckbox" type="checkbox" onchange="variantsChanged()"/>
+					<label for="xCheckbox">X</label>
+					<input id="windokuCheckbox" type="checkbox" onchange="variantsChanged()"/>
+					<label for="windokuCheckbox">Windoku</label>
+					<input id="antiknightCheckbox" type="checkbox" onchange="variantsChanged()"/>
+					<label for="antiknightCheckbox">Anti-knight</label>
+					<input id="antikingCheckbox" type="checkbox" onchange="variantsChanged()"/>
+					<label for="antikingCheckbox">Anti-king</label>
+					<input id="nonconsecutiveCheckbox" type="checkbox" onchange="variantsChanged()"/>
+					<label for="nonconsecutiveCheckbox">Non-consecutive</label>
+					&nbsp;
 					<input id="enterButton" type="button" value="Enter Puzzle" onclick="prepForManualEntry()"/>
 					&nbsp;
 					<input id="solveButton" type="button" value="Solve Puzzle" onclick="solvePuzzle()"/>
 					&nbsp;
+					<input id="explainButton" type="button" value="Explain" onclick="explainPuzzle()"/>
+					&nbsp;
 					<input id="delayRange" type="range" min="0" max="10" value="1" onchange="sendDelay()"/>
+					&nbsp;
+					<select id="algorithmSelect" onchange="algorithmChanged()">
+						<option value="backtrack">Backtracking</option>
+						<option value="dlx">Dancing links</option>
+					</select>
+					&nbsp;
+					<select id="orderSelect">
+						<option value="sequential">Left to right</option>
+						<option value="mrv">Fewest candidates first</option>
+					</select>
+					&nbsp;
+					<input id="propagateCheckbox" type="checkbox"/>
+					<label for="propagateCheckbox">Deduce singles</label>
+					&nbsp;
+					<span id="difficulty"></span>
 				</div>
-				<div id="grid" class="grid">
+				<div id="board">
+					<div id="grid" class="grid">
+					</div>
+					<div id="walkthrough">
+						<input type="button" value="Previous Step" onclick="showStep(currentStep-1)"/>
+						<input type="button" value="Next Step" onclick="showStep(currentStep+1)"/>
+						<ol id="steps"></ol>
+						<p id="stepsNote"></p>
+					</div>
 				</div>
 				<div id="keypad">
-					<input type="button" value="1" onclick="manualSet(1)"/>
-					<input type="button" value="2" onclick="manualSet(2)"/>
-					<input type="button" value="3" onclick="manualSet(3)"/>
-					<input type="button" value="4" onclick="manualSet(4)"/>
-					<input type="button" value="5" onclick="manualSet(5)"/>
-					<input type="button" value="6" onclick="manualSet(6)"/>
-					<input type="button" value="7" onclick="manualSet(7)"/>
-					<input type="button" value="8" onclick="manualSet(8)"/>
-					<input type="button" value="9" onclick="manualSet(9)"/>
-					<input type="button" value="✕" onclick="manualSet(0)"/>
 				</div>
 			</div>
 			<div id="error">
@@ -80,6 +199,243 @@ func StaticHTML(w io.Writer) {
 			<script type="text/javascript">
 				var globalSocket = null;
 
+				// the shape of the board - boxes are boxRows cells tall and
+				// boxColumns cells wide
+				var boxRows = 3;
+				var boxColumns = 3;
+				var size = 9;
+				var cellCount = 81;
+				var symbols = "123456789ABCDEFGHIJKLMNOP";
+				// the region of each cell for jigsaw puzzles, or null for boxes
+				var regions = null;
+				// the cages of a Killer Sudoku, each with a sum and a list of
+				// cell indexes, or null
+				var cages = null;
+				// the names of the built-in variants in play, such as "x"
+				var variants = [];
+				// the Kropki dots, each with the indexes of its two cells and a
+				// colour, and whether the negative rule applies, or null
+				var kropki = null;
+				// the thermometers, arrows and sandwich clues, in an object with
+				// the fields of a puzzle written as JSON, or null
+				var clues = null;
+				var clueNames = ["thermometers", "arrows", "sandwiches"];
+
+				function getBox() {
+					return boxRows + "x" + boxColumns;
+				}
+
+				function getBoardQuery() {
+					var query = "box=" + getBox();
+					if (regions != null) {
+						query += "&layout=" + regions.map(function(r) { return symbols.charAt(r); }).join("");
+					}
+					if (cages != null) {
+						query += "&cages=" + encodeURIComponent(JSON.stringify(cages));
+					}
+					if (variants.length > 0) {
+						query += "&variants=" + variants.join(",");
+					}
+					if (kropki != null) {
+						query += "&kropki=" + encodeURIComponent(JSON.stringify(kropki));
+					}
+					for (var k=0; clues != null && k<clueNames.length; k++) {
+						if (clues[clueNames[k]] != null) {
+							query += "&" + clueNames[k] + "=" + encodeURIComponent(JSON.stringify(clues[clueNames[k]]));
+						}
+					}
+					return query;
+				}
+
+				// isPlainGrid returns true for the usual 9x9 puzzles, which the
+				// algorithm options and explanations apply to
+				function isPlainGrid() {
+					return size == 9 && regions == null && cages == null && variants.length == 0 && kropki == null && clues == null;
+				}
+
+				function variantsChanged() {
+					variants = [];
+					var names = ["x", "windoku", "antiknight", "antiking", "nonconsecutive"];
+					for (var k=0; k<names.length; k++) {
+						if (document.getElementById(names[k] + "Checkbox").checked) {
+							variants.push(names[k]);
+						}
+					}
+					hideError();
+					clearConflicts();
+					drawOverlay();
+					algorithmChanged();
+					if (!isPlainGrid()) {
+						hideWalkthrough();
+					}
+					// a puzzle is ready to explain while it's ready to solve
+					document.getElementById("explainButton").disabled=(!isPlainGrid() || document.getElementById("solveButton").disabled);
+				}
+
+				// extraRegions returns the cells of the extra regions of the
+				// variants in play - the diagonals for X-Sudoku and the windows
+				// between the boxes for Windoku
+				function extraRegions() {
+					var extra = [];
+					if (variants.indexOf("x") >= 0) {
+						var down = [];
+						var up = [];
+						for (var r=0; r<size; r++) {
+							down.push(r*size + r);
+							up.push(r*size + size - 1 - r);
+						}
+						extra.push(down, up);
+					}
+					if (variants.indexOf("windoku") >= 0) {
+						for (var top=1; top+boxRows<size; top+=boxRows+1) {
+							for (var left=1; left+boxColumns<size; left+=boxColumns+1) {
+								var cells = [];
+								for (var r=top; r<top+boxRows; r++) {
+									for (var c=left; c<left+boxColumns; c++) {
+										cells.push(r*size + c);
+									}
+								}
+								extra.push(cells);
+							}
+						}
+					}
+					return extra;
+				}
+
+				function cagesChanged() {
+					var text = document.getElementById("cagesInput").value;
+					if (text.trim() == "") {
+						cages = null;
+					} else {
+						try {
+							var parsed = JSON.parse(text);
+							// accept a whole puzzle as well as just its cages
+							cages = Array.isArray(parsed) ? parsed : parsed.cages;
+						} catch (e) {
+							showError("The cages are not valid JSON: " + e.message);
+							return;
+						}
+					}
+					hideError();
+					drawOverlay();
+					algorithmChanged();
+					if (cages != null) {
+						// killer puzzles have to be typed in
+						prepForManualEntry();
+					}
+				}
+
+				function kropkiChanged() {
+					var text = document.getElementById("kropkiInput").value;
+					if (text.trim() == "") {
+						kropki = null;
+					} else {
+						try {
+							var parsed = JSON.parse(text);
+							// accept a whole puzzle as well as just its dots
+							kropki = ("kropki" in parsed) ? parsed.kropki : parsed;
+						} catch (e) {
+							showError("The Kropki dots are not valid JSON: " + e.message);
+							return;
+						}
+					}
+					hideError();
+					drawOverlay();
+					algorithmChanged();
+					if (kropki != null) {
+						// kropki puzzles have to be typed in
+						prepForManualEntry();
+					}
+				}
+
+				function cluesChanged() {
+					var text = document.getElementById("cluesInput").value;
+					if (text.trim() == "") {
+						clues = null;
+					} else {
+						try {
+							clues = JSON.parse(text);
+						} catch (e) {
+							showError("The thermometers, arrows and sandwiches are not valid JSON: " + e.message);
+							return;
+						}
+					}
+					hideError();
+					drawOverlay();
+					algorithmChanged();
+					if (clues != null) {
+						// these puzzles have to be typed in
+						prepForManualEntry();
+					}
+				}
+
+				function parseLayout(layout) {
+					// number the regions in the order they first appear, ignoring
+					// the separators the server ignores
+					var labels = {};
+					var count = 0;
+					var parsed = [];
+					for (var i=0; i<layout.length; i++) {
+						var c = layout.charAt(i);
+						if (" \t\r\n|-+,".indexOf(c) >= 0) { continue; }
+						if (!(c in labels)) {
+							labels[c] = count++;
+						}
+						parsed.push(labels[c]);
+					}
+					return parsed;
+				}
+
+				function layoutChanged() {
+					var layout = document.getElementById("layoutInput").value;
+					if (layout.trim() == "") {
+						regions = null;
+						sizeChanged();
+						return;
+					}
+					var parsed = parseLayout(layout);
+					if (parsed.length != cellCount) {
+						showError("The layout has " + parsed.length + " cells - expected " + cellCount + ".");
+						return;
+					}
+					regions = parsed;
+					hideError();
+					buildGrid();
+					algorithmChanged();
+					// jigsaw puzzles have to be typed in
+					prepForManualEntry();
+				}
+
+				function sizeChanged() {
+					var shape = document.getElementById("sizeSelect").value.split("x");
+					boxRows = parseInt(shape[0]);
+					boxColumns = parseInt(shape[1]);
+					size = boxRows * boxColumns;
+					cellCount = size * size;
+					// a layout, cages or dots only fit the size they were written
+					// for
+					regions = null;
+					cages = null;
+					kropki = null;
+					clues = null;
+					document.getElementById("layoutInput").value = "";
+					document.getElementById("cagesInput").value = "";
+					document.getElementById("kropkiInput").value = "";
+					document.getElementById("cluesInput").value = "";
+					hideWalkthrough();
+					hideError();
+					buildGrid();
+					buildKeypad();
+					algorithmChanged();
+					if (size == 9) {
+						document.getElementById("keypad").style.visibility="hidden";
+						loadPuzzle();
+					} else {
+						// puzzles of other sizes have to be typed in
+						prepForManualEntry();
+					}
+				}
+
 				function getDelay() {
 					return document.getElementById("delayRange").value;
 				}
@@ -93,6 +449,27 @@ func StaticHTML(w io.Writer) {
 					}
 				}
 
+				function getAlgorithm() {
+					return document.getElementById("algorithmSelect").value;
+				}
+
+				function algorithmChanged() {
+					// the algorithm options only apply to plain 9x9 puzzles, and
+					// cell order and propagation only apply to backtracking
+					var backtrack = (isPlainGrid() && getAlgorithm() == "backtrack");
+					document.getElementById("algorithmSelect").disabled = !isPlainGrid();
+					document.getElementById("orderSelect").disabled = !backtrack;
+					document.getElementById("propagateCheckbox").disabled = !backtrack;
+				}
+
+				function getOrder() {
+					return document.getElementById("orderSelect").value;
+				}
+
+				function getPropagate() {
+					return document.getElementById("propagateCheckbox").checked;
+				}
+
 				function showError(message) {
 					document.getElementById("errormessage").innerText = message;
 					document.getElementById("error").style.display="block";
@@ -112,6 +489,9 @@ func StaticHTML(w io.Writer) {
 									return;
 								}
 								populateGrid(reply.puzzle);
+								if (reply.rating != null) {
+									document.getElementById("difficulty").innerText = reply.rating.difficulty + " (" + reply.rating.value.toFixed(1) + ")";
+								}
 								return;
 						  } catch (e) {
 							  showError("Got an unexpected response while fetching JSON: " + e);
@@ -127,11 +507,86 @@ func StaticHTML(w io.Writer) {
 					xmlhttp.send();
 				}
 
+				function hideError() {
+					document.getElementById("error").style.visibility="hidden";
+				}
+
+				function clearConflicts() {
+					var conflicts = document.getElementsByClassName("conflict");
+					for (var i=conflicts.length-1; i>=0; i--) {
+						conflicts[i].classList.remove("conflict");
+					}
+				}
+
+				function showConflicts(cells) {
+					for (var i=0; i<cells.length; i++) {
+						document.getElementById("cell" + cells[i]).classList.add("conflict");
+					}
+				}
+
+				// returns the puzzle being worked on - the grid itself may be part
+				// way through a walkthrough
+				function getPuzzleState() {
+					if (explainStart != null) { return explainStart; }
+					return getGridState();
+				}
+
 				function solvePuzzle() {
+					hideError();
+					clearConflicts();
+					var state = getPuzzleState();
+					var xmlhttp = new XMLHttpRequest();
+					xmlhttp.onreadystatechange = function() {
+						if (this.readyState != 4) { return; }
+						if (this.status != 200) {
+							showError("Got an unexpected response while checking the puzzle: " + this.status);
+							return;
+						}
+						var reply;
+						try {
+							reply = JSON.parse(this.responseText);
+						} catch (e) {
+							showError("Got an unexpected response while checking the puzzle: " + e);
+							return;
+						}
+						if (reply.error != null) {
+							if (reply.cells != null) {
+								showConflicts(reply.cells);
+							}
+							showError(reply.error);
+							return;
+						}
+						if (reply.solutions == 0) {
+							showError("This puzzle has no solution.");
+							return;
+						}
+						if (!reply.unique && !confirm("This puzzle has more than one solution. Solve it anyway?")) {
+							return;
+						}
+						startSolve(state);
+					}
+					xmlhttp.open("GET", "/check/" + state + "?" + getBoardQuery(), true);
+					xmlhttp.send();
+				}
+
+				function startSolve(state) {
+					if (explainStart != null) {
+						// start from the puzzle, not as far as the walkthrough got
+						for (var i=0; i<cellCount; i++) {
+							setCell(i, state.charAt(i));
+						}
+					}
+					hideWalkthrough();
+					document.getElementById("explainButton").disabled=true;
 					document.getElementById("enterButton").disabled=true;
 					document.getElementById("solveButton").disabled=true;
 					document.getElementById("keypad").style.visibility="hidden";
-					var websocket = new WebSocket("ws://" + window.location.host + "/solve/" + getGridState());
+					var query = getBoardQuery();
+					// the server rejects algorithm options for other puzzles
+					if (isPlainGrid()) {
+						query += "&algorithm=" + getAlgorithm() + "&order=" + getOrder() + "&propagate=" + getPropagate();
+					}
+					var websocket = new WebSocket("ws://" + window.location.host + "/solve/" + state + "?" + query);
 					websocket.binaryType = 'arraybuffer';
 
 					websocket.onerror = function(evt) {
@@ -146,52 +601,407 @@ func StaticHTML(w io.Writer) {
 						if (evt.type != "message") { return; }
 						var data = new Uint8Array(evt.data);
 
+						// each update is the high and low bytes of the cell index,
+						// then the value
 						var len = data.length
-						if ((len < 2) || (len%2 == 1)) { return; }
-						for (var i=0; i<len; i+=2) {
-							var index = data[i];
-							var value = data[i+1];
-							if ((index < 81) && (value < 10)) {
+						if ((len < 3) || (len%3 != 0)) { return; }
+						for (var i=0; i<len; i+=3) {
+							var index = data[i]*256 + data[i+1];
+							var value = data[i+2];
+							if ((index < cellCount) && (value <= size)) {
 								setCell(index, value);
 							}
 						}
 					}
 					websocket.onclose = function (evt) {
 						globalSocket = null;
+						if (evt.reason) {
+							showError(evt.reason);
+						}
 					}
 				}
 	
+				var explainStart = null;
+				var explainSteps = [];
+				var currentStep = -1;
+
+				function explainPuzzle() {
+					hideError();
+					clearConflicts();
+					var state = getPuzzleState();
+					var xmlhttp = new XMLHttpRequest();
+					xmlhttp.onreadystatechange = function() {
+						if (this.readyState != 4) { return; }
+						if (this.status != 200) {
+							showError("Got an unexpected response while explaining the puzzle: " + this.status);
+							return;
+						}
+						var reply;
+						try {
+							reply = JSON.parse(this.responseText);
+						} catch (e) {
+							showError("Got an unexpected response while explaining the puzzle: " + e);
+							return;
+						}
+						if (reply.error != null) {
+							if (reply.cells != null) {
+								showConflicts(reply.cells);
+							}
+							showError(reply.error);
+							return;
+						}
+						showWalkthrough(state, reply);
+					}
+					xmlhttp.open("GET", "/explain/" + state, true);
+					xmlhttp.send();
+				}
+
+				function showWalkthrough(state, explanation) {
+					explainStart = state;
+					explainSteps = explanation.steps || [];
+					currentStep = -1;
+					var list = document.getElementById("steps");
+					list.innerHTML = "";
+					for (var i=0; i<explainSteps.length; i++) {
+						var item = document.createElement("li");
+						item.className = "step";
+						item.id = "step" + i;
+						var name = document.createElement("b");
+						name.innerText = explainSteps[i].technique + ": ";
+						item.appendChild(name);
+						item.appendChild(document.createTextNode(explainSteps[i].description));
+						item.onclick = (function(n) { return function() { showStep(n); }; })(i);
+						list.appendChild(item);
+					}
+					var note = "";
+					if (!explanation.solved) {
+						note = "None of the techniques apply from here on - the rest of the puzzle needs guessing.";
+					}
+					document.getElementById("stepsNote").innerText = note;
+					document.getElementById("walkthrough").style.visibility="visible";
+				}
+
+				function clearStepHighlights() {
+					var classes = ["pattern", "placed", "eliminated", "currentStep"];
+					for (var c=0; c<classes.length; c++) {
+						var elements = document.getElementsByClassName(classes[c]);
+						for (var i=elements.length-1; i>=0; i--) {
+							elements[i].classList.remove(classes[c]);
+						}
+					}
+				}
+
+				// shows the grid as it is after step n, highlighting the cells
+				// involved in the step
+				function showStep(n) {
+					if (explainStart == null || n < 0 || n >= explainSteps.length) { return; }
+					currentStep = n;
+					clearStepHighlights();
+					for (var i=0; i<cellCount; i++) {
+						setCell(i, explainStart.charAt(i));
+					}
+					for (var s=0; s<=n; s++) {
+						var placements = explainSteps[s].placements || [];
+						for (var i=0; i<placements.length; i++) {
+							setCell(placements[i].index, placements[i].digit);
+						}
+					}
+					var step = explainSteps[n];
+					for (var i=0; i<step.cells.length; i++) {
+						document.getElementById("cell" + step.cells[i]).classList.add("pattern");
+					}
+					var placements = step.placements || [];
+					for (var i=0; i<placements.length; i++) {
+						document.getElementById("cell" + placements[i].index).classList.add("placed");
+					}
+					var eliminations = step.eliminations || [];
+					for (var i=0; i<eliminations.length; i++) {
+						document.getElementById("cell" + eliminations[i].index).classList.add("eliminated");
+					}
+					var item = document.getElementById("step" + n);
+					item.classList.add("currentStep");
+					item.scrollIntoView({block: "nearest"});
+				}
+
+				function hideWalkthrough() {
+					clearStepHighlights();
+					explainStart = null;
+					document.getElementById("walkthrough").style.visibility="hidden";
+				}
+
 				function initPage() {
 					document.getElementById("solveButton").disabled=true;
+					document.getElementById("explainButton").disabled=true;
 					document.getElementById("enterButton").disabled=true;
 					document.getElementById("error").style.visibility="hidden";
 					buildGrid();
+					buildKeypad();
 					loadPuzzle();
 				}
 	
 				function buildGrid() {
-					var b = 1;
-					var boxStart;
-					var s;
 					var grid = document.getElementById("grid");
-					for (var b=0; b<9; b++) {
+					grid.innerHTML = "";
+					// keep the board about the same size whatever the number of cells
+					var cellSize = Math.min(60, Math.floor(540 / size));
+					if (regions != null) {
+						buildJigsawGrid(grid, cellSize);
+						drawOverlay();
+						return;
+					}
+					var boxesAcross = size / boxColumns;
+					grid.style.gridTemplateColumns = "repeat(" + boxesAcross + ", " + (boxColumns*cellSize) + "px)";
+					grid.style.gridTemplateRows = "repeat(" + boxColumns + ", " + (boxRows*cellSize) + "px)";
+					for (var b=0; b<size; b++) {
 						var box = document.createElement("div");
 						box.className = "box";
 						box.id = 'box' + b;
-						boxStart = parseInt(b/3)*27 + (b%3)*3;
-						for (var i=0; i<9; i++) {
-							var index = boxStart + parseInt(i/3)*6 + i;
+						box.style.gridTemplateColumns = "repeat(" + boxColumns + ", " + cellSize + "px)";
+						box.style.gridTemplateRows = "repeat(" + boxRows + ", " + cellSize + "px)";
+						var boxStart = Math.floor(b/boxesAcross)*boxRows*size + (b % boxesAcross)*boxColumns;
+						for (var i=0; i<size; i++) {
+							var index = boxStart + Math.floor(i/boxColumns)*size + i % boxColumns;
 							var cell = document.createElement("div");
 							cell.className = "cell dynamic";
 							cell.id = 'cell' + index;
+							if (size != 9) {
+								cell.style.fontSize = Math.floor(cellSize*2/3) + "px";
+								cell.style.padding = "0";
+								cell.style.lineHeight = cellSize + "px";
+							}
 							box.appendChild(cell);
 						}
 						grid.appendChild(box);
 					}
+					drawOverlay();
+				}
+
+				function buildJigsawGrid(grid, cellSize) {
+					// jigsaw regions can't be laid out as boxes, so the cells go
+					// straight into the grid, with thick borders between regions
+					grid.style.gridTemplateColumns = "repeat(" + size + ", " + cellSize + "px)";
+					grid.style.gridTemplateRows = "repeat(" + size + ", " + cellSize + "px)";
+					var thick = "3px solid rgba(0, 0, 0, 0.8)";
+					for (var index=0; index<cellCount; index++) {
+						var row = Math.floor(index / size);
+						var column = index % size;
+						var region = regions[index];
+						var cell = document.createElement("div");
+						cell.className = "cell dynamic";
+						cell.id = 'cell' + index;
+						if (row == 0 || regions[index-size] != region) { cell.style.borderTop = thick; }
+						if (row == size-1 || regions[index+size] != region) { cell.style.borderBottom = thick; }
+						if (column == 0 || regions[index-1] != region) { cell.style.borderLeft = thick; }
+						if (column == size-1 || regions[index+1] != region) { cell.style.borderRight = thick; }
+						if (size != 9) {
+							cell.style.fontSize = Math.floor(cellSize*2/3) + "px";
+							cell.style.padding = "0";
+							cell.style.lineHeight = cellSize + "px";
+						}
+						grid.appendChild(cell);
+					}
+				}
+
+				// drawOverlay shades the extra regions of any variants and draws
+				// the cages over the grid, with a dashed outline just inside
+				// each cage and its sum in the corner, any Kropki dots on the
+				// edges between cells, thermometers and arrows through the
+				// centres of their cells, and sandwich clues outside the grid
+				function drawOverlay() {
+					var grid = document.getElementById("grid");
+					var old = document.getElementById("overlay");
+					if (old != null) {
+						grid.removeChild(old);
+					}
+					var extra = extraRegions();
+					// sandwich clues need room above and to the left of the grid
+					var sandwiches = (clues != null && clues.sandwiches) ? clues.sandwiches : null;
+					grid.style.margin = (sandwiches != null) ? "24px 0 0 28px" : "";
+					if (cages == null && kropki == null && clues == null && extra.length == 0) { return; }
+					var ns = "http://www.w3.org/2000/svg";
+					var svg = document.createElementNS(ns, "svg");
+					svg.id = "overlay";
+					svg.setAttribute("width", grid.offsetWidth);
+					svg.setAttribute("height", grid.offsetHeight);
+					var origin = grid.getBoundingClientRect();
+					var inset = 4;
+					function line(x1, y1, x2, y2) {
+						var l = document.createElementNS(ns, "line");
+						l.setAttribute("class", "cageOutline");
+						l.setAttribute("x1", x1);
+						l.setAttribute("y1", y1);
+						l.setAttribute("x2", x2);
+						l.setAttribute("y2", y2);
+						svg.appendChild(l);
+					}
+					// cells on both diagonals are shaded twice, which marks the
+					// centre
+					for (var e=0; e<extra.length; e++) {
+						for (var k=0; k<extra[e].length; k++) {
+							var shaded = document.getElementById("cell" + extra[e][k]);
+							if (shaded == null) { continue; }
+							var sr = shaded.getBoundingClientRect();
+							var rect = document.createElementNS(ns, "rect");
+							rect.setAttribute("class", "extraRegion");
+							rect.setAttribute("x", sr.left - origin.left);
+							rect.setAttribute("y", sr.top - origin.top);
+							rect.setAttribute("width", sr.width);
+							rect.setAttribute("height", sr.height);
+							svg.appendChild(rect);
+						}
+					}
+					for (var c=0; cages != null && c<cages.length; c++) {
+						var cells = cages[c].cells;
+						var inCage = {};
+						var first = cells[0];
+						for (var k=0; k<cells.length; k++) {
+							inCage[cells[k]] = true;
+							first = Math.min(first, cells[k]);
+						}
+						for (var k=0; k<cells.length; k++) {
+							var index = cells[k];
+							var cell = document.getElementById("cell" + index);
+							if (cell == null) { continue; }
+							var r = cell.getBoundingClientRect();
+							var left = r.left - origin.left + inset;
+							var top = r.top - origin.top + inset;
+							var right = r.right - origin.left - inset;
+							var bottom = r.bottom - origin.top - inset;
+							var row = Math.floor(index / size);
+							var column = index % size;
+							// draw the sides which face another cage, running the
+							// lines on to the next cell where the cage continues
+							var upOpen = (row > 0 && inCage[index-size]);
+							var downOpen = (row < size-1 && inCage[index+size]);
+							var leftOpen = (column > 0 && inCage[index-1]);
+							var rightOpen = (column < size-1 && inCage[index+1]);
+							if (!upOpen) { line(leftOpen ? left-inset : left, top, rightOpen ? right+inset : right, top); }
+							if (!downOpen) { line(leftOpen ? left-inset : left, bottom, rightOpen ? right+inset : right, bottom); }
+							if (!leftOpen) { line(left, upOpen ? top-inset : top, left, downOpen ? bottom+inset : bottom); }
+							if (!rightOpen) { line(right, upOpen ? top-inset : top, right, downOpen ? bottom+inset : bottom); }
+						}
+						var firstCell = document.getElementById("cell" + first);
+						if (firstCell != null) {
+							var fr = firstCell.getBoundingClientRect();
+							var label = document.createElementNS(ns, "text");
+							label.setAttribute("class", "cageSum");
+							label.setAttribute("x", fr.left - origin.left + inset + 1);
+							label.setAttribute("y", fr.top - origin.top + inset + 10);
+							label.textContent = cages[c].sum;
+							svg.appendChild(label);
+						}
+					}
+					var dots = (kropki != null && kropki.dots) ? kropki.dots : [];
+					for (var d=0; d<dots.length; d++) {
+						var a = document.getElementById("cell" + dots[d].cells[0]);
+						var b = document.getElementById("cell" + dots[d].cells[1]);
+						if (a == null || b == null) { continue; }
+						var ar = a.getBoundingClientRect();
+						var br = b.getBoundingClientRect();
+						// the midpoint of the centres is on the shared edge
+						var dot = document.createElementNS(ns, "circle");
+						dot.setAttribute("class", dots[d].colour == "black" ? "blackDot" : "whiteDot");
+						dot.setAttribute("cx", (ar.left + ar.right + br.left + br.right) / 4 - origin.left);
+						dot.setAttribute("cy", (ar.top + ar.bottom + br.top + br.bottom) / 4 - origin.top);
+						dot.setAttribute("r", 5);
+						svg.appendChild(dot);
+					}
+					function centre(index) {
+						var cell = document.getElementById("cell" + index);
+						if (cell == null) { return null; }
+						var r = cell.getBoundingClientRect();
+						return {x: (r.left + r.right) / 2 - origin.left, y: (r.top + r.bottom) / 2 - origin.top, size: r.width};
+					}
+					function path(points, className, width) {
+						var p = document.createElementNS(ns, "polyline");
+						p.setAttribute("class", className);
+						p.setAttribute("points", points.map(function(pt) { return pt.x + "," + pt.y; }).join(" "));
+						if (width) {
+							p.setAttribute("stroke-width", width);
+						}
+						svg.appendChild(p);
+					}
+					function circle(at, radius, className) {
+						var c = document.createElementNS(ns, "circle");
+						c.setAttribute("class", className);
+						c.setAttribute("cx", at.x);
+						c.setAttribute("cy", at.y);
+						c.setAttribute("r", radius);
+						svg.appendChild(c);
+					}
+					var thermometers = (clues != null && clues.thermometers) ? clues.thermometers : [];
+					for (var t=0; t<thermometers.length; t++) {
+						var points = thermometers[t].map(centre).filter(function(pt) { return pt != null; });
+						if (points.length == 0) { continue; }
+						path(points, "thermometer", points[0].size / 4);
+						circle(points[0], points[0].size * 0.35, "thermometerBulb");
+					}
+					var arrows = (clues != null && clues.arrows) ? clues.arrows : [];
+					for (var a=0; a<arrows.length; a++) {
+						var from = centre(arrows[a].circle);
+						var steps = (arrows[a].cells || []).map(centre).filter(function(pt) { return pt != null; });
+						if (from == null || steps.length == 0) { continue; }
+						var radius = from.size * 0.4;
+						circle(from, radius, "arrow");
+						// start the arrow at the edge of its circle
+						var dx = steps[0].x - from.x;
+						var dy = steps[0].y - from.y;
+						var length = Math.sqrt(dx*dx + dy*dy);
+						steps.unshift({x: from.x + dx*radius/length, y: from.y + dy*radius/length});
+						path(steps, "arrow");
+						// the head points back along the last step at 30 degrees
+						// either side
+						var tip = steps[steps.length-1];
+						var back = steps[steps.length-2];
+						var angle = Math.atan2(back.y - tip.y, back.x - tip.x);
+						var head = from.size / 5;
+						path([
+							{x: tip.x + head*Math.cos(angle - Math.PI/6), y: tip.y + head*Math.sin(angle - Math.PI/6)},
+							tip,
+							{x: tip.x + head*Math.cos(angle + Math.PI/6), y: tip.y + head*Math.sin(angle + Math.PI/6)}
+						], "arrow");
+					}
+					function sandwichClue(anchor, x, y, clue) {
+						if (clue == null || clue < 0) { return; }
+						var label = document.createElementNS(ns, "text");
+						label.setAttribute("class", "sandwichClue");
+						label.setAttribute("x", x);
+						label.setAttribute("y", y);
+						label.setAttribute("text-anchor", anchor);
+						label.textContent = clue;
+						svg.appendChild(label);
+					}
+					for (var k=0; sandwiches != null && k<size; k++) {
+						var rowStart = centre(k*size);
+						var columnTop = centre(k);
+						if (rowStart != null && sandwiches.rows) {
+							sandwichClue("end", rowStart.x - rowStart.size/2 - 6, rowStart.y + 5, sandwiches.rows[k]);
+						}
+						if (columnTop != null && sandwiches.columns) {
+							sandwichClue("middle", columnTop.x, columnTop.y - columnTop.size/2 - 6, sandwiches.columns[k]);
+						}
+					}
+					grid.appendChild(svg);
+				}
+
+				function buildKeypad() {
+					var keypad = document.getElementById("keypad");
+					keypad.innerHTML = "";
+					for (var v=0; v<=size; v++) {
+						var button = document.createElement("input");
+						button.type = "button";
+						button.value = (v == 0) ? "\u2715" : symbols.charAt(v-1);
+						button.onclick = (function(n) { return function() { manualSet(n); }; })(v);
+						keypad.appendChild(button);
+					}
 				}
 
 				function prepForManualEntry() {
+					hideWalkthrough();
+					document.getElementById("difficulty").innerText = "";
 					document.getElementById("enterButton").disabled=true;
+					document.getElementById("solveButton").disabled=false;
+					document.getElementById("explainButton").disabled=!isPlainGrid();
 					document.getElementById("keypad").style.display="block";
 					document.getElementById("keypad").style.visibility="visible";
 					resetGrid();
@@ -206,12 +1016,12 @@ func StaticHTML(w io.Writer) {
 						cell.innerText = "";
 					} else {
 						cell.className = "cell static highlighted";
-						cell.innerText = value;
+						cell.innerText = symbols.charAt(value-1);
 					}
 				}
 
 				function resetGrid() {
-					for (var i=0; i<81; i++) {
+					for (var i=0; i<cellCount; i++) {
 						var cell = document.getElementById("cell" + i);
 						cell.className = "cell dynamic";
 						cell.innerText = "";
@@ -221,15 +1031,15 @@ func StaticHTML(w io.Writer) {
 
 				function highlightCell(evt) {
 					var hl = document.getElementsByClassName("highlighted");
-					for (var i=0; i<hl.length; i++) {
-						hl[i].className = hl[i].className.substring(0, hl[i].className.lastIndexOf(" "));
+					for (var i=hl.length-1; i>=0; i--) {
+						hl[i].classList.remove("highlighted");
 					}
-					evt.target.className += " highlighted";
+					evt.target.classList.add("highlighted");
 				}
 
 				function getGridState() {
 					var state = "";
-					for (var i=0; i<81; i++) {
+					for (var i=0; i<cellCount; i++) {
 						var value = document.getElementById("cell" + i).innerText;
 						if (value == "") { value = "0"; }
 						state = state + value;
@@ -237,20 +1047,28 @@ func StaticHTML(w io.Writer) {
 					return state;
 				}
 
+				// sets a cell to a value, which is either a number or a symbol
+				// from a puzzle string
 				function setCell(index, value) {
 					var cell = document.getElementById("cell" + index);
-					if (value == "0") { value = ""; }
+					if (typeof value == "number") {
+						value = (value == 0) ? "" : symbols.charAt(value-1);
+					} else if (value == "0") {
+						value = "";
+					}
 					cell.innerText = value;
 				}
 	
 				function populateGrid(state) {
 					var s;
-					for (var i=0; i<81; i++) {
+					for (var i=0; i<cellCount; i++) {
 						s = state.charAt(i);
-						if (s != 0) { document.getElementById("cell" + i).className = "cell static"; }
+						if (s != "0") { document.getElementById("cell" + i).className = "cell static"; }
 						setCell(i, state.charAt(i));
 					}
 					document.getElementById("solveButton").disabled=false;
+					// only plain 9x9 puzzles can be explained
+					document.getElementById("explainButton").disabled=!isPlainGrid();
 					document.getElementById("enterButton").disabled=false;
 				}
 
@@ -0,0 +1,242 @@
+package solver
+
+import "fmt"
+
+// NonConsecutiveConstraint is the rule that cells side by side, or one above
+// the other, don't hold consecutive values.
+type NonConsecutiveConstraint struct{}
+
+// Forbidden returns the values one more or one less than the values beside,
+// above and below cell i.
+func (NonConsecutiveConstraint) Forbidden(b *Board, i int) uint32 {
+	var forbidden uint32
+	for _, j := range movesFrom(b.Size(), i, orthogonalMoves) {
+		forbidden |= consecutiveTo(b.Cells[j])
+	}
+	return forbidden & (1<<uint(b.Size()) - 1)
+}
+
+// Check adds a Violation to e for every pair of neighbouring cells holding
+// consecutive values.
+func (NonConsecutiveConstraint) Check(b *Board, e *ValidationError) {
+	forNeighbours(b, func(i, j int) {
+		if consecutive(b.Cells[i], b.Cells[j]) {
+			e.Violations = append(e.Violations, Violation{
+				Rule:  "non-consecutive",
+				Cells: []int{i, j},
+				Msg:   fmt.Sprintf("%s and %s are consecutive", valueAt(b, i), valueAt(b, j)),
+			})
+		}
+	})
+}
+
+// DotColour is the colour of a Kropki dot.
+type DotColour string
+
+// A white dot joins consecutive values, such as 4 and 5, and a black dot
+// values where one is double the other, such as 3 and 6. A dot between 1 and
+// 2 may be either colour.
+const (
+	WhiteDot DotColour = "white"
+	BlackDot DotColour = "black"
+)
+
+// Dot is a Kropki dot on the edge between two neighbouring cells.
+type Dot struct {
+	// Cells holds the indexes of the two cells.
+	Cells  [2]int    `json:"cells"`
+	Colour DotColour `json:"colour"`
+}
+
+// KropkiConstraint is the rule that the values either side of each dot are
+// consecutive for a white dot, or one double the other for a black dot.
+type KropkiConstraint struct {
+	Dots []Dot `json:"dots"`
+	// Negative adds the rule that every pair of values which could have a
+	// dot does have one, so neighbouring cells without a dot are neither
+	// consecutive nor one double the other.
+	Negative bool `json:"negative,omitempty"`
+}
+
+// Forbidden returns the values which don't fit the dots on the edges of cell
+// i, given the values in the cells on the other side.
+func (k KropkiConstraint) Forbidden(b *Board, i int) uint32 {
+	size := b.Size()
+	all := uint32(1)<<uint(size) - 1
+	allowed := all
+	var dotted []int
+	for _, d := range k.Dots {
+		j, ok := d.other(i)
+		if !ok || j < 0 || j >= len(b.Cells) {
+			continue
+		}
+		dotted = append(dotted, j)
+		if value := b.Cells[j]; value > 0 {
+			allowed &= d.Colour.partners(value)
+		}
+	}
+	if k.Negative {
+		for _, j := range movesFrom(size, i, orthogonalMoves) {
+			if value := b.Cells[j]; value > 0 && !containsInt(dotted, j) {
+				allowed &^= WhiteDot.partners(value) | BlackDot.partners(value)
+			}
+		}
+	}
+	return all &^ allowed
+}
+
+// Check adds a Violation to e for every dot whose values don't fit its
+// colour, every dot which isn't between neighbouring cells, and with the
+// negative rule every pair of neighbouring cells without a dot whose values
+// would fit one.
+func (k KropkiConstraint) Check(b *Board, e *ValidationError) {
+	size := b.Size()
+	dotted := make(map[[2]int]bool)
+	for n, d := range k.Dots {
+		i, j := d.Cells[0], d.Cells[1]
+		if i > j {
+			i, j = j, i
+		}
+		if !d.valid(size) {
+			e.Violations = append(e.Violations, Violation{
+				Rule: "kropki",
+				Msg:  d.problem(n),
+			})
+			continue
+		}
+		dotted[[2]int{i, j}] = true
+		if b.Cells[i] < 1 || b.Cells[j] < 1 || d.Colour.joins(b.Cells[i], b.Cells[j]) {
+			continue
+		}
+		relation := "consecutive"
+		if d.Colour == BlackDot {
+			relation = "in a 1:2 ratio"
+		}
+		e.Violations = append(e.Violations, Violation{
+			Rule:  "kropki",
+			Cells: []int{i, j},
+			Msg:   fmt.Sprintf("%s and %s are not %s across a %s dot", valueAt(b, i), valueAt(b, j), relation, d.Colour),
+		})
+	}
+	if !k.Negative {
+		return
+	}
+	forNeighbours(b, func(i, j int) {
+		if dotted[[2]int{i, j}] || b.Cells[i] < 1 || b.Cells[j] < 1 {
+			return
+		}
+		if WhiteDot.joins(b.Cells[i], b.Cells[j]) || BlackDot.joins(b.Cells[i], b.Cells[j]) {
+			e.Violations = append(e.Violations, Violation{
+				Rule:  "kropki",
+				Cells: []int{i, j},
+				Msg:   fmt.Sprintf("%s and %s have no dot between them but would fit one", valueAt(b, i), valueAt(b, j)),
+			})
+		}
+	})
+}
+
+// checkShape returns an error unless every dot is white or black and between
+// neighbouring cells of a board with size rows.
+func (k KropkiConstraint) checkShape(size int) error {
+	for n, d := range k.Dots {
+		if !d.valid(size) {
+			return fmt.Errorf("%s", d.problem(n))
+		}
+	}
+	return nil
+}
+
+// valid reports whether the dot is white or black and between neighbouring
+// cells of a board with size rows.
+func (d Dot) valid(size int) bool {
+	i, j := d.Cells[0], d.Cells[1]
+	if i > j {
+		i, j = j, i
+	}
+	if i < 0 || j >= size*size || !containsInt(movesFrom(size, i, orthogonalMoves), j) {
+		return false
+	}
+	return d.Colour == WhiteDot || d.Colour == BlackDot
+}
+
+// problem describes what is wrong with dot n, counting from 0, if it isn't
+// valid.
+func (d Dot) problem(n int) string {
+	return fmt.Sprintf("dot %d is not a white or black dot between neighbouring cells", n+1)
+}
+
+// other returns the cell on the other side of the dot from cell i, if the
+// dot touches cell i.
+func (d Dot) other(i int) (int, bool) {
+	switch i {
+	case d.Cells[0]:
+		return d.Cells[1], true
+	case d.Cells[1]:
+		return d.Cells[0], true
+	}
+	return 0, false
+}
+
+// joins reports whether two values fit a dot of this colour.
+func (c DotColour) joins(a, b int) bool {
+	if c == BlackDot {
+		return a == 2*b || b == 2*a
+	}
+	return consecutive(a, b)
+}
+
+// partners returns the values which fit a dot of this colour next to value,
+// with value v in bit v-1.
+func (c DotColour) partners(value int) uint32 {
+	if c != BlackDot {
+		return consecutiveTo(value)
+	}
+	partners := maskOfValue(2 * value)
+	if value%2 == 0 {
+		partners |= maskOfValue(value / 2)
+	}
+	return partners
+}
+
+var orthogonalMoves = [][2]int{{-1, 0}, {0, -1}, {0, 1}, {1, 0}}
+
+// forNeighbours calls f for every pair of cells side by side or one above
+// the other, with i before j.
+func forNeighbours(b *Board, f func(i, j int)) {
+	size := b.Size()
+	for i := range b.Cells {
+		if i%size < size-1 {
+			f(i, i+1)
+		}
+		if i+size < len(b.Cells) {
+			f(i, i+size)
+		}
+	}
+}
+
+func consecutive(a, b int) bool {
+	return a > 0 && b > 0 && (a == b+1 || b == a+1)
+}
+
+// consecutiveTo returns the values one more and one less than value, with
+// value v in bit v-1, or 0 for an empty cell.
+func consecutiveTo(value int) uint32 {
+	if value < 1 {
+		return 0
+	}
+	return maskOfValue(value-1) | maskOfValue(value+1)
+}
+
+// maskOfValue returns the bit for a value on a Board, or 0 if it is out of
+// range.
+func maskOfValue(value int) uint32 {
+	if value < 1 || value > MaxBoardSize {
+		return 0
+	}
+	return 1 << uint(value-1)
+}
+
+// valueAt describes the value in a cell, such as 4 at r1c2.
+func valueAt(b *Board, i int) string {
+	return fmt.Sprintf("%c at %s", symbol(b.Cells[i]), cellNameOn(i, b.Size()))
+}
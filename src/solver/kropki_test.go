@@ -0,0 +1,133 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKropkiForbidden(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	b.Cells[40] = 4
+	// the cells beside and above r5c5 can't hold 3 or 5
+	if forbidden := (NonConsecutiveConstraint{}).Forbidden(b, 31); forbidden != 0x14 {
+		t.Errorf("expected 3 and 5 to be forbidden - got %x", forbidden)
+	}
+	if forbidden := (NonConsecutiveConstraint{}).Forbidden(b, 30); forbidden != 0 {
+		t.Errorf("expected nothing to be forbidden diagonally - got %x", forbidden)
+	}
+
+	b.Cells[0], b.Cells[2] = 3, 5
+	k := KropkiConstraint{Dots: []Dot{{[2]int{0, 1}, WhiteDot}, {[2]int{9, 0}, BlackDot}}}
+	if forbidden := k.Forbidden(b, 1); forbidden != 0x1f5 {
+		t.Errorf("expected all but 2 and 4 to be forbidden - got %x", forbidden)
+	}
+	if forbidden := k.Forbidden(b, 9); forbidden != 0x1df {
+		t.Errorf("expected all but 6 to be forbidden - got %x", forbidden)
+	}
+	// without a dot between r1c2 and r1c3, r1c2 can't hold 4 or 6 next to
+	// the 5
+	k.Negative = true
+	if forbidden := k.Forbidden(b, 1); forbidden != 0x1fd {
+		t.Errorf("expected all but 2 to be forbidden - got %x", forbidden)
+	}
+}
+
+func TestKropkiSolve(t *testing.T) {
+	// put every dot the solution of the killer puzzle has
+	s, _ := ParseBoard(killerSolution, 0, 0)
+	k := KropkiConstraint{Negative: true}
+	forNeighbours(s, func(i, j int) {
+		if WhiteDot.joins(s.Cells[i], s.Cells[j]) {
+			k.Dots = append(k.Dots, Dot{[2]int{i, j}, WhiteDot})
+		} else if BlackDot.joins(s.Cells[i], s.Cells[j]) {
+			k.Dots = append(k.Dots, Dot{[2]int{i, j}, BlackDot})
+		}
+	})
+	b, _ := NewBoard(3, 3)
+	b.Constraints = []Constraint{k}
+	if count, err := b.CountSolutions(context.Background(), 0); err != nil || count != 1 {
+		t.Errorf("expected a unique solution - got %d, %v", count, err)
+	}
+	if err := b.Solve(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if b.String() != killerSolution {
+		t.Errorf("expected %s - got %s instead", killerSolution, b)
+	}
+
+	// every value from 1 to 4 would fit a dot next to 2, so with no dots the
+	// negative rule leaves nowhere for it
+	b, _ = NewBoard(2, 2)
+	b.Constraints = []Constraint{KropkiConstraint{Negative: true}}
+	if count, err := b.CountSolutions(context.Background(), 0); err != nil || count != 0 {
+		t.Errorf("expected no solutions - got %d, %v", count, err)
+	}
+}
+
+func TestKropkiValidate(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	b.Constraints = []Constraint{
+		NonConsecutiveConstraint{},
+		KropkiConstraint{
+			Dots: []Dot{
+				{[2]int{0, 1}, WhiteDot},
+				{[2]int{1, 2}, BlackDot},
+				{[2]int{0, 10}, WhiteDot},
+				{[2]int{3, 4}, "grey"},
+			},
+			Negative: true,
+		},
+	}
+	b.Cells[0], b.Cells[1], b.Cells[2] = 3, 4, 7
+	b.Cells[9] = 6
+	err := b.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError - got %v", err)
+	}
+	expected := []Violation{
+		{"non-consecutive", []int{0, 1}, "3 at r1c1 and 4 at r1c2 are consecutive"},
+		{"kropki", []int{1, 2}, "4 at r1c2 and 7 at r1c3 are not in a 1:2 ratio across a black dot"},
+		{"kropki", nil, "dot 3 is not a white or black dot between neighbouring cells"},
+		{"kropki", nil, "dot 4 is not a white or black dot between neighbouring cells"},
+		{"kropki", []int{0, 9}, "3 at r1c1 and 6 at r2c1 have no dot between them but would fit one"},
+	}
+	if !reflect.DeepEqual(verr.Violations, expected) {
+		t.Errorf("expected %v - got %v instead", expected, verr.Violations)
+	}
+	if !strings.Contains(err.Error(), expected[1].Msg) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestKropkiJSON(t *testing.T) {
+	text := `{"variants":["nonconsecutive"],"kropki":{"dots":[{"cells":[0,1],"colour":"white"},{"cells":[1,10],"colour":"black"}],"negative":true}}`
+	var b Board
+	if err := json.Unmarshal([]byte(text), &b); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []Constraint{
+		NonConsecutiveConstraint{},
+		KropkiConstraint{Dots: []Dot{{[2]int{0, 1}, WhiteDot}, {[2]int{1, 10}, BlackDot}}, Negative: true},
+	}
+	if !reflect.DeepEqual(b.Constraints, expected) {
+		t.Errorf("expected %v - got %v instead", expected, b.Constraints)
+	}
+	data, _ := json.Marshal(&b)
+	if !strings.Contains(string(data), text[1:len(text)-1]) {
+		t.Errorf("expected the variants and dots in %s", data)
+	}
+
+	for _, text := range []string{
+		`{"kropki":{"dots":[{"cells":[0,2],"colour":"white"}]}}`,
+		`{"kropki":{"dots":[{"cells":[0,1],"colour":"grey"}]}}`,
+		`{"kropki":{"dots":[{"cells":[80,81],"colour":"black"}]}}`,
+	} {
+		if err := json.Unmarshal([]byte(text), &b); err == nil || !strings.Contains(err.Error(), "dot 1 is not") {
+			t.Errorf("%s: expected an error for a bad dot - got %v", text, err)
+		}
+	}
+}
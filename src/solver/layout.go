@@ -0,0 +1,119 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxBoardSize is the largest number of rows a Board can have.
+const MaxBoardSize = 25
+
+// layout holds the lookup tables for a Board of one shape.
+type layout struct {
+	size       int
+	boxRows    int
+	boxColumns int
+	// units lists the cells in each row, column and box, in that order
+	units [][]int
+	// unitsOf lists the units each cell belongs to
+	unitsOf [][]int
+	// boxName is "box", or "region" for jigsaw boards
+	boxName string
+}
+
+var layouts = make(map[[2]int]*layout)
+var layoutsLock sync.Mutex
+
+// layoutFor returns the layout for boards with boxes boxRows tall and
+// boxColumns wide. Layouts are built once and shared.
+func layoutFor(boxRows, boxColumns int) *layout {
+	layoutsLock.Lock()
+	defer layoutsLock.Unlock()
+	key := [2]int{boxRows, boxColumns}
+	if l, ok := layouts[key]; ok {
+		return l
+	}
+
+	size := boxRows * boxColumns
+	l := newLayout(size, "box", func(i int) int {
+		row, column := i/size, i%size
+		return (row/boxRows)*boxRows + column/boxColumns
+	})
+	l.boxRows, l.boxColumns = boxRows, boxColumns
+	layouts[key] = l
+	return l
+}
+
+// layoutForRegions returns the layout for a jigsaw board, whose regions take
+// the place of boxes. Unlike layoutFor it builds a new layout every time, so
+// boards build theirs once when they are made.
+func layoutForRegions(regions []int) *layout {
+	size := int(math.Sqrt(float64(len(regions))))
+	return newLayout(size, "region", func(i int) int { return regions[i] })
+}
+
+func newLayout(size int, boxName string, boxOf func(i int) int) *layout {
+	l := &layout{
+		size:    size,
+		units:   make([][]int, 3*size),
+		unitsOf: make([][]int, size*size),
+		boxName: boxName,
+	}
+	for i := 0; i < size*size; i++ {
+		row, column := i/size, i%size
+		for _, u := range []int{row, size + column, 2*size + boxOf(i)} {
+			l.units[u] = append(l.units[u], i)
+			l.unitsOf[i] = append(l.unitsOf[i], u)
+		}
+	}
+	return l
+}
+
+// unitName returns the name of unit u, such as "row".
+func (l *layout) unitName(u int) string {
+	if u >= 2*l.size {
+		return l.boxName
+	}
+	return unitNames[u/l.size]
+}
+
+// BoxShape returns the usual box shape for a board with size rows - as
+// square as possible, and wider than it is tall. Returns an error if the only
+// shape is a single row, which would make the boxes the same as the rows.
+func BoxShape(size int) (boxRows, boxColumns int, err error) {
+	if size < 1 || size > MaxBoardSize {
+		return 0, 0, fmt.Errorf("board size %d is not from 1 to %d", size, MaxBoardSize)
+	}
+	boxRows = 1
+	for r := 2; r*r <= size; r++ {
+		if size%r == 0 {
+			boxRows = r
+		}
+	}
+	if boxRows == 1 && size > 1 {
+		return 0, 0, fmt.Errorf("no box shape fits a board of size %d", size)
+	}
+	return boxRows, size / boxRows, nil
+}
+
+// ParseBoxShape parses a box shape written as rows x columns, such as 2x3
+// for a 6x6 board.
+func ParseBoxShape(s string) (boxRows, boxColumns int, err error) {
+	parts := strings.Split(strings.ToLower(s), "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("box shape %s is not rows x columns", s)
+	}
+	if boxRows, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+		return 0, 0, fmt.Errorf("box shape %s is not rows x columns", s)
+	}
+	if boxColumns, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+		return 0, 0, fmt.Errorf("box shape %s is not rows x columns", s)
+	}
+	if boxRows < 1 || boxColumns < 1 || boxRows*boxColumns > MaxBoardSize {
+		return 0, 0, fmt.Errorf("box shape %s does not fit a board of at most %d rows", s, MaxBoardSize)
+	}
+	return boxRows, boxColumns, nil
+}
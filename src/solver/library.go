@@ -0,0 +1,264 @@
+package solver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LibraryEntry is a puzzle stored in a Library.
+type LibraryEntry struct {
+	Puzzle     string     `json:"puzzle"`
+	Source     string     `json:"source,omitempty"`
+	Difficulty Difficulty `json:"difficulty"`
+	// Rating is the value of the puzzle's Rating when it was imported.
+	Rating float64   `json:"rating"`
+	Tags   []string  `json:"tags,omitempty"`
+	Added  time.Time `json:"added"`
+	// Canonical is the puzzle's canonical form, which is the same for every
+	// shuffled copy of the puzzle.
+	Canonical string `json:"canonical,omitempty"`
+}
+
+// HasTag reports whether the entry has the tag, ignoring case.
+func (e LibraryEntry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// LibraryFilter selects entries in a Library. The zero value selects every
+// entry.
+type LibraryFilter struct {
+	// Tags lists tags which entries must all have.
+	Tags []string
+	// Difficulties lists the acceptable difficulties - any difficulty is
+	// acceptable if it is empty.
+	Difficulties []Difficulty
+}
+
+func (f LibraryFilter) matches(e LibraryEntry) bool {
+	for _, tag := range f.Tags {
+		if !e.HasTag(tag) {
+			return false
+		}
+	}
+	if len(f.Difficulties) == 0 {
+		return true
+	}
+	for _, d := range f.Difficulties {
+		if e.Difficulty == d {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportOptions is the metadata given to every puzzle imported by
+// Library.Import.
+type ImportOptions struct {
+	Source string
+	Tags   []string
+	// Difficulty overrides the rated difficulty if it is not nil.
+	Difficulty *Difficulty
+}
+
+// ImportResult counts what happened to the puzzles given to Library.Import.
+type ImportResult struct {
+	Added int
+	// Duplicates are already in the library, perhaps shuffled.
+	Duplicates int
+	// Rejected puzzles are invalid or don't have a unique solution.
+	Rejected int
+}
+
+// Library is a collection of puzzles stored on disk as JSON lines, one
+// LibraryEntry per line. Puzzles with the same canonical form are only
+// stored once. A Library is safe for concurrent use.
+type Library struct {
+	path    string
+	lock    sync.Mutex
+	entries []LibraryEntry
+	// the index of each entry by canonical form
+	index map[Grid]int
+	rand  *rand.Rand
+}
+
+// OpenLibrary reads the library stored at path. The file is created when the
+// first puzzle is added if it does not exist.
+func OpenLibrary(path string) (*Library, error) {
+	l := &Library{
+		path:  path,
+		index: make(map[Grid]int),
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry LibraryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		grid, err := NewGridFromString(entry.Puzzle)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		var key Grid
+		if entry.Canonical != "" {
+			key, err = NewGridFromString(entry.Canonical)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+			}
+		} else {
+			key = grid.Canonical()
+			entry.Canonical = key.String()
+		}
+		if _, ok := l.index[key]; ok {
+			continue
+		}
+		l.index[key] = len(l.entries)
+		l.entries = append(l.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	return l, nil
+}
+
+// Len returns the number of puzzles in the library.
+func (l *Library) Len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return len(l.entries)
+}
+
+// Import adds every puzzle in r, in any format accepted by ParseGrids, with
+// the metadata in options. Puzzles are rated unless options sets their
+// difficulty.
+func (l *Library) Import(r io.Reader, options ImportOptions) (ImportResult, error) {
+	var result ImportResult
+	grids, err := ParseGrids(r)
+	if err != nil {
+		return result, err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return result, err
+	}
+	out := bufio.NewWriter(f)
+	now := time.Now().UTC()
+	for _, grid := range grids {
+		key := grid.Canonical()
+		if _, ok := l.index[key]; ok {
+			result.Duplicates++
+			continue
+		}
+		if grid.Validate() != nil || !grid.HasUniqueSolution() {
+			result.Rejected++
+			continue
+		}
+		entry := LibraryEntry{
+			Puzzle:    grid.String(),
+			Source:    options.Source,
+			Tags:      options.Tags,
+			Added:     now,
+			Canonical: key.String(),
+		}
+		if rating, err := grid.Rate(); err == nil {
+			entry.Rating = rating.Value
+			entry.Difficulty = rating.Difficulty
+		}
+		if options.Difficulty != nil {
+			entry.Difficulty = *options.Difficulty
+		}
+		b, err := json.Marshal(entry)
+		if err != nil {
+			f.Close()
+			return result, err
+		}
+		out.Write(b)
+		out.WriteByte('\n')
+		l.index[key] = len(l.entries)
+		l.entries = append(l.entries, entry)
+		result.Added++
+	}
+	if err := out.Flush(); err != nil {
+		f.Close()
+		return result, fmt.Errorf("could not write to %s: %v", l.path, err)
+	}
+	return result, f.Close()
+}
+
+// Entries returns the entries selected by filter, in the order they were
+// added.
+func (l *Library) Entries(filter LibraryFilter) []LibraryEntry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	var entries []LibraryEntry
+	for _, entry := range l.entries {
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Tags returns every tag in the library with the number of entries which
+// have it.
+func (l *Library) Tags() map[string]int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	tags := make(map[string]int)
+	for _, entry := range l.entries {
+		for _, tag := range entry.Tags {
+			tags[tag]++
+		}
+	}
+	return tags
+}
+
+// Random returns a random entry selected by filter.
+func (l *Library) Random(filter LibraryFilter) (LibraryEntry, error) {
+	entries := l.Entries(filter)
+	if len(entries) == 0 {
+		return LibraryEntry{}, fmt.Errorf("no puzzles in %s match", l.path)
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return entries[l.rand.Intn(len(entries))], nil
+}
+
+// Puzzle returns a random puzzle from the library.
+func (l *Library) Puzzle(ctx context.Context) (Grid, error) {
+	entry, err := l.Random(LibraryFilter{})
+	if err != nil {
+		return Grid{}, err
+	}
+	return NewGridFromString(entry.Puzzle)
+}
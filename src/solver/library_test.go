@@ -0,0 +1,110 @@
+package solver
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tempLibrary(t *testing.T) (*Library, string, func()) {
+	dir, err := ioutil.TempDir("", "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "puzzles.jsonl")
+	l, err := OpenLibrary(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return l, path, func() { os.RemoveAll(dir) }
+}
+
+// shuffle swaps the digits 1 and 2 in a puzzle and transposes it
+func shuffle(puzzle string) string {
+	grid, _ := NewGridFromString(strings.NewReplacer("1", "2", "2", "1").Replace(puzzle))
+	t := IdentityTransform()
+	t.Transpose = true
+	return t.Apply(grid).String()
+}
+
+func TestLibraryImport(t *testing.T) {
+	l, path, cleanup := tempLibrary(t)
+	defer cleanup()
+
+	input := strings.Join(easyPuzzles, "\n") + "\n" +
+		shuffle(easyPuzzles[0]) + "\n" +
+		// two 5s in the first row
+		"55" + strings.Repeat("0", 79) + "\n" +
+		// many solutions
+		strings.Repeat("0", 81) + "\n"
+	result, err := l.Import(strings.NewReader(input), ImportOptions{Source: "test", Tags: []string{"Easy", "newspaper"}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := ImportResult{Added: len(easyPuzzles), Duplicates: 1, Rejected: 2}
+	if result != expected {
+		t.Errorf("expected %+v - got %+v instead", expected, result)
+	}
+
+	hard := Hard
+	result, err = l.Import(strings.NewReader(hardPuzzles[0]+"\n"+easyPuzzles[1]), ImportOptions{Difficulty: &hard})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected = ImportResult{Added: 1, Duplicates: 1}
+	if result != expected {
+		t.Errorf("expected %+v - got %+v instead", expected, result)
+	}
+
+	// everything should be read back from disk
+	l, err = OpenLibrary(path)
+	if err != nil {
+		t.Fatalf("could not reopen library: %v", err)
+	}
+	if l.Len() != len(easyPuzzles)+1 {
+		t.Errorf("expected %d puzzles - got %d instead", len(easyPuzzles)+1, l.Len())
+	}
+	entries := l.Entries(LibraryFilter{Tags: []string{"NEWSPAPER"}})
+	if len(entries) != len(easyPuzzles) {
+		t.Fatalf("expected %d tagged puzzles - got %d instead", len(easyPuzzles), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Puzzle != easyPuzzles[i] || entry.Source != "test" || entry.Rating == 0 || entry.Added.IsZero() || entry.Canonical == "" {
+			t.Errorf("unexpected entry %+v", entry)
+		}
+	}
+	if tags := l.Tags(); tags["Easy"] != len(easyPuzzles) || tags["newspaper"] != len(easyPuzzles) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	for n := 0; n < 5; n++ {
+		entry, err := l.Random(LibraryFilter{Difficulties: []Difficulty{Hard}})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if entry.Puzzle != hardPuzzles[0] {
+			t.Errorf("expected %s - got %s instead", hardPuzzles[0], entry.Puzzle)
+		}
+	}
+	if _, err := l.Random(LibraryFilter{Tags: []string{"missing"}}); err == nil {
+		t.Error("expected an error when no puzzles match")
+	}
+	if _, err := l.Puzzle(context.Background()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestOpenLibraryErrors(t *testing.T) {
+	_, path, cleanup := tempLibrary(t)
+	defer cleanup()
+	if err := ioutil.WriteFile(path, []byte("{\"puzzle\":\"123\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenLibrary(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("expected an error on line 1 - got %v", err)
+	}
+}
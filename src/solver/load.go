@@ -2,6 +2,7 @@ package solver
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -9,21 +10,50 @@ import (
 )
 
 const (
-	timeout = 30
-	url     = "http://davidbau.com/generated/sudoku.txt"
+	davidbauTimeout = 30 * time.Second
+	davidbauURL     = "http://davidbau.com/generated/sudoku.txt"
 )
 
+// DavidBauSource scrapes puzzles from the text puzzle generator at
+// davidbau.com, or from anything serving the same format.
+type DavidBauSource struct {
+	// URL defaults to http://davidbau.com/generated/sudoku.txt.
+	URL string
+	// Timeout defaults to 30 seconds.
+	Timeout time.Duration
+}
+
 // LoadPuzzle loads a new grid from a puzzle generator.
 func LoadPuzzle() (Grid, error) {
+	return DavidBauSource{}.Puzzle(context.Background())
+}
+
+// Puzzle loads a new grid from the generator.
+func (s DavidBauSource) Puzzle(ctx context.Context) (Grid, error) {
 	grid := Grid{}
 
-	client := http.Client{Timeout: time.Duration(timeout * time.Second)}
-	resp, err := client.Get(url)
+	url := s.URL
+	if url == "" {
+		url = davidbauURL
+	}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = davidbauTimeout
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return grid, err
+	}
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Do(req.WithContext(ctx))
 	if err != nil {
 		return grid, fmt.Errorf("could not load puzzle from %s: %v", url, err)
 	}
 
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return grid, fmt.Errorf("could not load puzzle from %s: %s", url, resp.Status)
+	}
 	scanner := bufio.NewScanner(resp.Body)
 	var value int
 	i := 0
@@ -50,6 +80,9 @@ func LoadPuzzle() (Grid, error) {
 			i++
 		}
 	}
+	if i < 81 {
+		return grid, fmt.Errorf("expected 81 cells from %s - got %d instead", url, i)
+	}
 
 	return grid, nil
 }
@@ -0,0 +1,112 @@
+package solver
+
+import "math/bits"
+
+// digitMask is a set of digits, with digit d stored in bit d-1.
+type digitMask uint16
+
+// allDigits has the bits for digits 1 to 9 set.
+const allDigits digitMask = 0x1ff
+
+// lookup tables mapping a cell index to its row, column and box
+var rowOf, columnOf, boxOf [81]int
+
+// units lists the cells in each row, column and box, in that order
+var units [27][9]int
+
+// peers lists the 20 other cells sharing a row, column or box with each cell
+var peers [81][20]int
+
+func init() {
+	for i := 0; i < 81; i++ {
+		rowOf[i] = i / 9
+		columnOf[i] = i % 9
+		boxOf[i] = (rowOf[i]/3)*3 + columnOf[i]/3
+		units[rowOf[i]][columnOf[i]] = i
+		units[9+columnOf[i]][rowOf[i]] = i
+		units[18+boxOf[i]][(rowOf[i]%3)*3+columnOf[i]%3] = i
+	}
+	for i := 0; i < 81; i++ {
+		n := 0
+		for j := 0; j < 81; j++ {
+			if j != i && sees(i, j) {
+				peers[i][n] = j
+				n++
+			}
+		}
+	}
+}
+
+// sees returns true if two cells share a row, column or box.
+func sees(a, b int) bool {
+	return rowOf[a] == rowOf[b] || columnOf[a] == columnOf[b] || boxOf[a] == boxOf[b]
+}
+
+func maskOf(digit int) digitMask {
+	if digit < 1 || digit > 9 {
+		return 0
+	}
+	return 1 << uint(digit-1)
+}
+
+func (m digitMask) count() int {
+	return bits.OnesCount16(uint16(m))
+}
+
+func (m digitMask) has(digit int) bool {
+	return m&maskOf(digit) != 0
+}
+
+// returns -1 if the mask is empty
+func (m digitMask) lowest() int {
+	if m == 0 {
+		return -1
+	}
+	return bits.TrailingZeros16(uint16(m)) + 1
+}
+
+// digits returns the digits in the mask in ascending order.
+func (m digitMask) digits() []int {
+	var digits []int
+	for ; m != 0; m &= m - 1 {
+		digits = append(digits, m.lowest())
+	}
+	return digits
+}
+
+// occupancy tracks the digits placed in every row, column and box so that the
+// candidates for a cell can be looked up without scanning the grid.
+type occupancy struct {
+	rows    [9]digitMask
+	columns [9]digitMask
+	boxes   [9]digitMask
+}
+
+func newOccupancy(grid *Grid) occupancy {
+	var o occupancy
+	for i, value := range grid {
+		if value != 0 {
+			o.place(i, value)
+		}
+	}
+	return o
+}
+
+func (o *occupancy) place(index, digit int) {
+	m := maskOf(digit)
+	o.rows[rowOf[index]] |= m
+	o.columns[columnOf[index]] |= m
+	o.boxes[boxOf[index]] |= m
+}
+
+func (o *occupancy) remove(index, digit int) {
+	m := maskOf(digit)
+	o.rows[rowOf[index]] &^= m
+	o.columns[columnOf[index]] &^= m
+	o.boxes[boxOf[index]] &^= m
+}
+
+// candidates returns the digits which can be placed in the cell at index.
+func (o *occupancy) candidates(index int) digitMask {
+	return allDigits &^ (o.rows[rowOf[index]] | o.columns[columnOf[index]] | o.boxes[boxOf[index]])
+}
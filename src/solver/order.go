@@ -0,0 +1,72 @@
+package solver
+
+import "fmt"
+
+// CellOrder determines which empty cell the solver branches on next.
+type CellOrder int
+
+const (
+	// Sequential visits the empty cells from left to right, top to bottom.
+	Sequential CellOrder = iota
+	// MinimumRemaining visits the empty cell with the fewest candidates first.
+	MinimumRemaining
+)
+
+var cellOrderNames = map[CellOrder]string{
+	Sequential:       "sequential",
+	MinimumRemaining: "mrv",
+}
+
+func (order CellOrder) String() string {
+	if name, ok := cellOrderNames[order]; ok {
+		return name
+	}
+	return fmt.Sprintf("CellOrder(%d)", int(order))
+}
+
+// ParseCellOrder returns the CellOrder with the given name. An empty name
+// returns Sequential.
+func ParseCellOrder(name string) (CellOrder, error) {
+	if name == "" {
+		return Sequential, nil
+	}
+	for order, orderName := range cellOrderNames {
+		if orderName == name {
+			return order, nil
+		}
+	}
+	return Sequential, fmt.Errorf("unknown cell order %s", name)
+}
+
+// nextCell returns the next empty cell to branch on along with its
+// candidates. For Sequential, from is the index of the first cell which may
+// still be empty - every cell is considered otherwise. Returns -1 if there are no empty cells left.
+func (grid *Grid) nextCell(occupied *occupancy, order CellOrder, from int) (int, digitMask) {
+	if order != MinimumRemaining {
+		for i := from; i < 81; i++ {
+			if grid[i] == 0 {
+				return i, occupied.candidates(i)
+			}
+		}
+		return -1, 0
+	}
+
+	best := -1
+	var bestCandidates digitMask
+	bestCount := 10
+	for i := 0; i < 81; i++ {
+		if grid[i] != 0 {
+			continue
+		}
+		candidates := occupied.candidates(i)
+		count := candidates.count()
+		if count < bestCount {
+			best, bestCandidates, bestCount = i, candidates, count
+			// can't do better than a forced single or a dead end
+			if count <= 1 {
+				break
+			}
+		}
+	}
+	return best, bestCandidates
+}
@@ -0,0 +1,238 @@
+package solver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// ParseError describes a problem found while parsing a puzzle.
+type ParseError struct {
+	// Line and Column locate the problem, counting from 1. Column counts
+	// runes rather than bytes.
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// ParseGrid parses a single puzzle. It accepts:
+//
+//   - 81 characters on one line, using 0 or . for empty cells
+//   - the output of Grid.Print, with | - and + separators
+//   - nine lines of nine cells, optionally separated by whitespace
+//   - the SadMan .sdk, .sdm and .ss file formats
+//
+// Empty cells may be written as 0 . _ * x or X. Lines starting with # and
+// section headers such as [Puzzle] are ignored.
+func ParseGrid(s string) (Grid, error) {
+	grids, starts, err := parseGrids(strings.NewReader(s))
+	if err != nil {
+		return Grid{}, err
+	}
+	switch len(grids) {
+	case 0:
+		return Grid{}, &ParseError{Line: 1, Column: 1, Msg: "no puzzle found"}
+	case 1:
+		return grids[0], nil
+	}
+	return Grid{}, &ParseError{Line: starts[1].line, Column: starts[1].column, Msg: fmt.Sprintf("expected one puzzle - found %d", len(grids))}
+}
+
+// position is the line and column of a rune in a puzzle, counting from 1.
+type position struct {
+	line, column int
+}
+
+// ParseGrids parses every puzzle in r, in any of the formats accepted by
+// ParseGrid. Puzzles follow each other, so a file with one 81 character
+// puzzle per line, such as an .sdm file, returns every puzzle in the file.
+func ParseGrids(r io.Reader) ([]Grid, error) {
+	grids, _, err := parseGrids(r)
+	return grids, err
+}
+
+// parseGrids is ParseGrids, but also returns where each puzzle starts.
+func parseGrids(r io.Reader) ([]Grid, []position, error) {
+	var grids []Grid
+	var starts []position
+	var grid Grid
+	cells := 0
+	line := 0
+	// the line the last puzzle ended on, and where its last cell was
+	completed := 0
+	lastLine, lastColumn := 0, 0
+	inState := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			// .sdk files may follow the puzzle with the solver's state,
+			// which we don't want
+			inState = strings.EqualFold(text, "[State]")
+			continue
+		}
+		if inState {
+			continue
+		}
+
+		column := 0
+		for _, r := range scanner.Text() {
+			column++
+			if isSeparator(r) {
+				continue
+			}
+			value, ok := cellValue(r)
+			if !ok {
+				return grids, starts, &ParseError{Line: line, Column: column, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+			if cells == 0 && completed == line {
+				return grids, starts, &ParseError{Line: line, Column: column, Msg: "more than 81 cells in puzzle"}
+			}
+			if cells == 0 {
+				starts = append(starts, position{line, column})
+			}
+			grid[cells] = value
+			cells++
+			lastLine, lastColumn = line, column
+			if cells == 81 {
+				grids = append(grids, grid)
+				grid = Grid{}
+				cells = 0
+				completed = line
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return grids, starts, err
+	}
+	if cells != 0 {
+		return grids, starts, &ParseError{Line: lastLine, Column: lastColumn + 1, Msg: fmt.Sprintf("puzzle ended after %d cells - expected 81", cells)}
+	}
+	return grids, starts, nil
+}
+
+// ParseBoard parses a single puzzle of any size, in the layouts accepted by
+// ParseGrid, with values above 9 written as letters from A. Empty cells may
+// be written as 0 . _ * x or X. The board has boxes boxRows tall and
+// boxColumns wide, or if both are 0 its size is worked out from the number of
+// cells and its boxes have the shape returned by BoxShape.
+func ParseBoard(s string, boxRows, boxColumns int) (*Board, error) {
+	type cell struct {
+		r            rune
+		line, column int
+	}
+	var cells []cell
+	line := 0
+	inState := false
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			inState = strings.EqualFold(text, "[State]")
+			continue
+		}
+		if inState {
+			continue
+		}
+		column := 0
+		for _, r := range scanner.Text() {
+			column++
+			if !isSeparator(r) {
+				cells = append(cells, cell{r, line, column})
+			}
+		}
+	}
+	end := &ParseError{Line: line, Column: 1, Msg: "no puzzle found"}
+	if len(cells) > 0 {
+		last := cells[len(cells)-1]
+		end = &ParseError{Line: last.line, Column: last.column + 1}
+	}
+
+	if boxRows == 0 && boxColumns == 0 {
+		size := int(math.Sqrt(float64(len(cells))))
+		if len(cells) == 0 {
+			return nil, end
+		}
+		if size*size != len(cells) {
+			end.Msg = fmt.Sprintf("%d cells do not make a square board", len(cells))
+			return nil, end
+		}
+		var err error
+		if boxRows, boxColumns, err = BoxShape(size); err != nil {
+			end.Msg = err.Error()
+			return nil, end
+		}
+	}
+	b, err := NewBoard(boxRows, boxColumns)
+	if err != nil {
+		return nil, err
+	}
+	if len(cells) != len(b.Cells) {
+		end.Msg = fmt.Sprintf("puzzle has %d cells - expected %d", len(cells), len(b.Cells))
+		return nil, end
+	}
+	for i, c := range cells {
+		value, ok := cellValue(c.r)
+		if !ok || value != 0 {
+			value = symbolValue(c.r)
+		}
+		if value < 0 || value > b.Size() {
+			return nil, &ParseError{Line: c.line, Column: c.column, Msg: fmt.Sprintf("unexpected character %q for a %dx%d board", c.r, b.Size(), b.Size())}
+		}
+		b.Cells[i] = value
+	}
+	return b, nil
+}
+
+// ParseJigsaw parses a jigsaw puzzle of any size, with the puzzle in a form
+// accepted by ParseBoard and the layout of its regions in the form accepted
+// by ParseRegions.
+func ParseJigsaw(puzzle, layout string) (*Board, error) {
+	regions, err := ParseRegions(layout)
+	if err != nil {
+		return nil, fmt.Errorf("layout: %v", err)
+	}
+	empty, err := NewJigsawBoard(regions)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ParseBoard(puzzle, empty.BoxRows, empty.BoxColumns)
+	if err != nil {
+		return nil, err
+	}
+	b.Regions, b.jigsaw = empty.Regions, empty.jigsaw
+	return b, nil
+}
+
+func isSeparator(r rune) bool {
+	switch r {
+	case '|', '-', '+', ' ', '\t', '\r', ',':
+		return true
+	}
+	return false
+}
+
+func cellValue(r rune) (int, bool) {
+	if r >= '1' && r <= '9' {
+		return int(r - '0'), true
+	}
+	switch r {
+	case '0', '.', '_', '*', 'x', 'X':
+		return 0, true
+	}
+	return 0, false
+}
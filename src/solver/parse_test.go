@@ -0,0 +1,143 @@
+package solver
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseGrid(t *testing.T) {
+	expected, _ := NewGridFromString(testPuzzle)
+	tables := []struct {
+		name  string
+		input string
+	}{
+		{"digits", testPuzzle},
+		{"dots", strings.Replace(testPuzzle, "0", ".", -1)},
+		{"surrounding whitespace", "\n  " + testPuzzle + "  \n"},
+		{"rows", `
+009060000
+040010000
+050700320
+890400070
+000507000
+002009180
+400000002
+005000760
+060200400
+`},
+		{"whitespace separated", `
+0 0 9 0 6 0 0 0 0
+0 4 0 0 1 0 0 0 0
+0 5 0 7 0 0 3 2 0
+8 9 0 4 0 0 0 7 0
+0 0 0 5 0 7 0 0 0
+0 0 2 0 0 9 1 8 0
+4 0 0 0 0 0 0 0 2
+0 0 5 0 0 0 7 6 0
+0 6 0 2 0 0 4 0 0
+`},
+		{"sdk", `#A Someone
+#D A comment
+[Puzzle]
+..9.6....
+.4..1....
+.5.7..32.
+89.4...7.
+...5.7...
+..2..918.
+4.......2
+..5...76.
+.6.2..4..
+[State]
+123456789
+`},
+		{"ss", `..9|.6.|...
+.4.|.1.|...
+.5.|7..|32.
+-----------
+89.|4..|.7.
+...|5.7|...
+..2|..9|18.
+-----------
+4..|...|..2
+..5|...|76.
+.6.|2..|4..
+`},
+	}
+	for _, table := range tables {
+		grid, err := ParseGrid(table.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", table.name, err)
+			continue
+		}
+		if grid != expected {
+			t.Errorf("%s: expected %s - got %s instead", table.name, expected, grid)
+		}
+	}
+}
+
+func TestParseGridRoundTrip(t *testing.T) {
+	for _, s := range mixedPuzzles {
+		grid, _ := NewGridFromString(s)
+		var b bytes.Buffer
+		grid.Print(&b)
+		parsed, err := ParseGrid(b.String())
+		if err != nil {
+			t.Errorf("could not parse the printed form of %s: %v", s, err)
+			continue
+		}
+		if parsed != grid {
+			t.Errorf("expected %s to round trip - got %s instead", s, parsed)
+		}
+	}
+}
+
+func TestParseGridErrors(t *testing.T) {
+	tables := []struct {
+		name   string
+		input  string
+		line   int
+		column int
+	}{
+		{"empty", "", 1, 1},
+		{"short", "123", 1, 4},
+		{"bad character", "009060000\n04a010000", 2, 3},
+		{"too long", testPuzzle + "1", 1, 82},
+		{"two puzzles", testPuzzle + "\n" + testPuzzle, 2, 1},
+		{"indented second puzzle", testPuzzle + "\n# another\n  " + testPuzzle, 3, 3},
+	}
+	for _, table := range tables {
+		_, err := ParseGrid(table.input)
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%s: expected a *ParseError - got %v instead", table.name, err)
+			continue
+		}
+		if perr.Line != table.line || perr.Column != table.column {
+			t.Errorf("%s: expected an error at line %d, column %d - got %v instead", table.name, table.line, table.column, perr)
+		}
+	}
+}
+
+func TestParseGrids(t *testing.T) {
+	input := "# an .sdm file\n" + strings.Join(hardPuzzles, "\n") + "\n"
+	grids, err := ParseGrids(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(grids) != len(hardPuzzles) {
+		t.Fatalf("expected %d puzzles - got %d instead", len(hardPuzzles), len(grids))
+	}
+	for i, grid := range grids {
+		if grid.String() != hardPuzzles[i] {
+			t.Errorf("expected %s - got %s instead", hardPuzzles[i], grid)
+		}
+	}
+}
+
+func TestNewGridFromStringShort(t *testing.T) {
+	if _, err := NewGridFromString("123"); err == nil {
+		t.Error("expected an error for a short string")
+	}
+}
@@ -0,0 +1,250 @@
+package solver
+
+import "fmt"
+
+// ThermometerConstraint is the rule that values rise along each thermometer,
+// from its bulb to its tip.
+type ThermometerConstraint struct {
+	// Thermometers holds the cells of each thermometer, starting with the
+	// bulb.
+	Thermometers [][]int `json:"thermometers"`
+}
+
+// Forbidden returns the values which leave no room for the cells before and
+// after cell i on its thermometers, given the values already in them.
+func (t ThermometerConstraint) Forbidden(b *Board, i int) uint32 {
+	size := b.Size()
+	all := uint32(1)<<uint(size) - 1
+	allowed := all
+	for _, cells := range t.Thermometers {
+		p := indexOf(cells, i)
+		if p == -1 {
+			continue
+		}
+		// each cell before i needs a smaller value, and each cell after a
+		// larger one
+		low, high := p+1, size-(len(cells)-1-p)
+		for q, j := range cells {
+			value := b.Cells[j]
+			if value < 1 || q == p {
+				continue
+			}
+			if q < p && value+p-q > low {
+				low = value + p - q
+			}
+			if q > p && value-(q-p) < high {
+				high = value - (q - p)
+			}
+		}
+		allowed &= valueRange(low, high, size)
+	}
+	return all &^ allowed
+}
+
+// Check adds a Violation to e for every value which doesn't rise from the
+// one before it on a thermometer, or leaves no room for the cells before or
+// after it, and for a thermometer which isn't a path across the board.
+func (t ThermometerConstraint) Check(b *Board, e *ValidationError) {
+	size := b.Size()
+	if err := t.checkShape(size); err != nil {
+		e.Violations = append(e.Violations, Violation{Rule: "thermometer", Msg: err.Error()})
+		return
+	}
+	for n, cells := range t.Thermometers {
+		last := -1
+		for p, i := range cells {
+			value := b.Cells[i]
+			if value < 1 || value > size {
+				continue
+			}
+			if value < p+1 || value > size-(len(cells)-1-p) {
+				e.Violations = append(e.Violations, Violation{
+					Rule:  "thermometer",
+					Cells: []int{i},
+					Msg:   fmt.Sprintf("%s leaves no room for the rest of thermometer %d", valueAt(b, i), n+1),
+				})
+			}
+			if last != -1 {
+				j := cells[last]
+				var problem string
+				switch {
+				case value <= b.Cells[j]:
+					problem = "don't rise"
+				case value-b.Cells[j] < p-last:
+					problem = "leave no room to rise"
+				}
+				if problem != "" {
+					e.Violations = append(e.Violations, Violation{
+						Rule:  "thermometer",
+						Cells: []int{j, i},
+						Msg:   fmt.Sprintf("%s and %s %s along thermometer %d", valueAt(b, j), valueAt(b, i), problem, n+1),
+					})
+				}
+			}
+			last = p
+		}
+	}
+}
+
+// checkShape returns an error unless every thermometer is a path of 2 to
+// size cells on a board with size rows.
+func (t ThermometerConstraint) checkShape(size int) error {
+	for n, cells := range t.Thermometers {
+		if len(cells) < 2 || len(cells) > size {
+			return fmt.Errorf("thermometer %d has %d cells - expected 2 to %d", n+1, len(cells), size)
+		}
+		if err := checkPath(fmt.Sprintf("thermometer %d", n+1), cells, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Arrow is an arrow leading from a circled cell, whose value is the sum of
+// the values along the arrow. Values may repeat along an arrow.
+type Arrow struct {
+	Circle int `json:"circle"`
+	// Cells holds the cells along the arrow, starting next to the circle.
+	Cells []int `json:"cells"`
+}
+
+// ArrowConstraint is the rule that the values along each arrow add up to the
+// value in its circle.
+type ArrowConstraint struct {
+	Arrows []Arrow `json:"arrows"`
+}
+
+// Forbidden returns the values which can't make up, or be made up by, the
+// other cells of the arrows through cell i, given the values already in
+// them.
+func (c ArrowConstraint) Forbidden(b *Board, i int) uint32 {
+	size := b.Size()
+	all := uint32(1)<<uint(size) - 1
+	allowed := all
+	for _, a := range c.Arrows {
+		if i != a.Circle && !containsInt(a.Cells, i) {
+			continue
+		}
+		// the sum of the other cells along the arrow, and how many are empty
+		sum, empty := 0, 0
+		for _, j := range a.Cells {
+			switch value := b.Cells[j]; {
+			case j == i:
+			case value < 1:
+				empty++
+			default:
+				sum += value
+			}
+		}
+		switch circle := b.Cells[a.Circle]; {
+		case i == a.Circle:
+			allowed &= valueRange(sum+empty, sum+empty*size, size)
+		case circle > 0:
+			allowed &= valueRange(circle-sum-empty*size, circle-sum-empty, size)
+		default:
+			allowed &= valueRange(1, size-sum-empty, size)
+		}
+	}
+	return all &^ allowed
+}
+
+// Check adds a Violation to e for every arrow whose values don't add up to
+// the value in its circle, or already add up to more, and for an arrow which
+// isn't a path across the board.
+func (c ArrowConstraint) Check(b *Board, e *ValidationError) {
+	size := b.Size()
+	if err := c.checkShape(size); err != nil {
+		e.Violations = append(e.Violations, Violation{Rule: "arrow", Msg: err.Error()})
+		return
+	}
+	for n, a := range c.Arrows {
+		circle := b.Cells[a.Circle]
+		if circle < 1 || circle > size {
+			continue
+		}
+		// empty cells need at least 1 each
+		sum, empty := 0, 0
+		for _, j := range a.Cells {
+			if value := b.Cells[j]; value < 1 {
+				empty++
+			} else {
+				sum += value
+			}
+		}
+		var msg string
+		switch {
+		case empty == 0 && sum != circle:
+			msg = fmt.Sprintf("arrow %d adds up to %d, not the %s in its circle", n+1, sum, valueAt(b, a.Circle))
+		case empty > 0 && sum+empty > circle:
+			msg = fmt.Sprintf("arrow %d adds up to more than the %s in its circle", n+1, valueAt(b, a.Circle))
+		default:
+			continue
+		}
+		e.Violations = append(e.Violations, Violation{
+			Rule:  "arrow",
+			Cells: append([]int{a.Circle}, a.Cells...),
+			Msg:   msg,
+		})
+	}
+}
+
+// checkShape returns an error unless every arrow leads from its circle along
+// a path of at least one cell on a board with size rows.
+func (c ArrowConstraint) checkShape(size int) error {
+	for n, a := range c.Arrows {
+		if len(a.Cells) == 0 {
+			return fmt.Errorf("arrow %d has no cells", n+1)
+		}
+		if err := checkPath(fmt.Sprintf("arrow %d", n+1), append([]int{a.Circle}, a.Cells...), size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPath returns an error unless the cells of a path, such as thermometer
+// 1, are on a board with size rows, each once, and each touches the one
+// before it side by side or diagonally.
+func checkPath(name string, cells []int, size int) error {
+	for k, i := range cells {
+		if i < 0 || i >= size*size {
+			return fmt.Errorf("%s has cell %d, which is not on the board", name, i)
+		}
+		if indexOf(cells[:k], i) != -1 {
+			return fmt.Errorf("%s has %s twice", name, cellNameOn(i, size))
+		}
+		if k == 0 {
+			continue
+		}
+		j := cells[k-1]
+		if rows, columns := i/size-j/size, i%size-j%size; rows < -1 || rows > 1 || columns < -1 || columns > 1 {
+			return fmt.Errorf("%s jumps from %s to %s", name, cellNameOn(j, size), cellNameOn(i, size))
+		}
+	}
+	return nil
+}
+
+// valueRange returns the values from low to high on a board with size rows,
+// with value v in bit v-1.
+func valueRange(low, high, size int) uint32 {
+	if low < 1 {
+		low = 1
+	}
+	if high > size {
+		high = size
+	}
+	if low > high {
+		return 0
+	}
+	return (uint32(1)<<uint(high) - 1) &^ (uint32(1)<<uint(low-1) - 1)
+}
+
+// indexOf returns the position of value in values, or -1 if it isn't there.
+func indexOf(values []int, value int) int {
+	for k, v := range values {
+		if v == value {
+			return k
+		}
+	}
+	return -1
+}
@@ -0,0 +1,161 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPathSolve(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint Constraint
+		puzzle     string
+	}{
+		{
+			"thermometers",
+			ThermometerConstraint{[][]int{{1, 2, 3, 4, 5}, {23, 24, 25, 26}, {46, 47, 39}, {44, 52, 61}, {63, 55, 45}, {72, 73, 74, 65, 64, 54}}},
+			"000008000000005000090300000050000403000000001000020800000030000000400000300006070",
+		},
+		{
+			"arrows",
+			ArrowConstraint{[]Arrow{{6, []int{14, 22}}, {27, []int{18, 10}}, {42, []int{33, 34, 44}}, {54, []int{63, 72, 73}}, {17, []int{16, 7, 15}}}},
+			"000600000000005000000300500050000000000000001000020800001030000007400030300006070",
+		},
+	}
+	for _, test := range tests {
+		b, _ := ParseBoard(test.puzzle, 0, 0)
+		if count, err := b.CountSolutions(context.Background(), 2); err != nil || count != 2 {
+			t.Errorf("%s: expected more than one solution without the %s - got %d, %v", test.name, test.name, count, err)
+		}
+		b.Constraints = []Constraint{test.constraint}
+		if count, err := b.CountSolutions(context.Background(), 0); err != nil || count != 1 {
+			t.Errorf("%s: expected a unique solution - got %d, %v", test.name, count, err)
+		}
+		if err := b.Solve(context.Background(), nil); err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if b.String() != killerSolution {
+			t.Errorf("%s: expected %s - got %s instead", test.name, killerSolution, b)
+		}
+	}
+}
+
+func TestPathForbidden(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	thermometer := ThermometerConstraint{[][]int{{0, 1, 2}}}
+	b.Cells[0] = 3
+	// 4 to 8 leave room for the 3 in the bulb and a larger value at the tip
+	if forbidden := thermometer.Forbidden(b, 1); forbidden != 0x107 {
+		t.Errorf("expected 1, 2, 3 and 9 to be forbidden - got %x", forbidden)
+	}
+	b.Cells[2] = 6
+	if forbidden := thermometer.Forbidden(b, 1); forbidden != 0x1e7 {
+		t.Errorf("expected all but 4 and 5 to be forbidden - got %x", forbidden)
+	}
+
+	b, _ = NewBoard(3, 3)
+	arrow := ArrowConstraint{[]Arrow{{0, []int{1, 2}}}}
+	if forbidden := arrow.Forbidden(b, 0); forbidden != 0x1 {
+		t.Errorf("expected 1 to be forbidden in the circle - got %x", forbidden)
+	}
+	if forbidden := arrow.Forbidden(b, 1); forbidden != 0x100 {
+		t.Errorf("expected 9 to be forbidden on the arrow - got %x", forbidden)
+	}
+	b.Cells[0], b.Cells[2] = 7, 4
+	if forbidden := arrow.Forbidden(b, 1); forbidden != 0x1fb {
+		t.Errorf("expected all but 3 to be forbidden - got %x", forbidden)
+	}
+}
+
+func TestPathValidate(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	b.Constraints = []Constraint{
+		ThermometerConstraint{[][]int{{0, 1, 2}, {10, 11, 12}}},
+		ArrowConstraint{[]Arrow{{18, []int{19, 20}}, {27, []int{28, 29}}}},
+	}
+	b.Cells[0], b.Cells[1] = 5, 4
+	b.Cells[10], b.Cells[12] = 2, 3
+	b.Cells[18], b.Cells[19], b.Cells[20] = 9, 2, 3
+	b.Cells[27], b.Cells[28] = 2, 5
+	err := b.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError - got %v", err)
+	}
+	expected := []Violation{
+		{"thermometer", []int{0, 1}, "5 at r1c1 and 4 at r1c2 don't rise along thermometer 1"},
+		{"thermometer", []int{10, 12}, "2 at r2c2 and 3 at r2c4 leave no room to rise along thermometer 2"},
+		{"arrow", []int{18, 19, 20}, "arrow 1 adds up to 5, not the 9 at r3c1 in its circle"},
+		{"arrow", []int{27, 28, 29}, "arrow 2 adds up to more than the 2 at r4c1 in its circle"},
+	}
+	if !reflect.DeepEqual(verr.Violations, expected) {
+		t.Errorf("expected %v - got %v instead", expected, verr.Violations)
+	}
+	if !strings.Contains(err.Error(), expected[2].Msg) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	b, _ = NewBoard(3, 3)
+	b.Constraints = []Constraint{ThermometerConstraint{[][]int{{0, 1, 2, 3, 4, 5, 6, 7}}}}
+	b.Cells[6] = 9
+	if err := b.Validate(); err == nil || !strings.Contains(err.Error(), "9 at r1c7 leaves no room for the rest of thermometer 1") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	tests := []struct {
+		cells    []int
+		expected string
+	}{
+		{[]int{0, 1, 11, 19}, ""},
+		{[]int{0, 2}, "jumps from r1c1 to r1c3"},
+		{[]int{8, 9}, "jumps from r1c9 to r2c1"},
+		{[]int{0, 1, 0}, "has r1c1 twice"},
+		{[]int{80, 81}, "has cell 81, which is not on the board"},
+	}
+	for _, test := range tests {
+		err := checkPath("thermometer 1", test.cells, 9)
+		switch {
+		case test.expected == "" && err != nil:
+			t.Errorf("%v: unexpected error %v", test.cells, err)
+		case test.expected != "" && (err == nil || err.Error() != "thermometer 1 "+test.expected):
+			t.Errorf("%v: expected thermometer 1 %s - got %v", test.cells, test.expected, err)
+		}
+	}
+}
+
+func TestPathJSON(t *testing.T) {
+	text := `{"thermometers":[[0,1,2]],"arrows":[{"circle":9,"cells":[10,11]}],"sandwiches":{"rows":[-1,0,35],"columns":[5]}}`
+	var b Board
+	if err := json.Unmarshal([]byte(text), &b); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []Constraint{
+		ThermometerConstraint{[][]int{{0, 1, 2}}},
+		ArrowConstraint{[]Arrow{{9, []int{10, 11}}}},
+		SandwichConstraint{Rows: []int{-1, 0, 35}, Columns: []int{5}},
+	}
+	if !reflect.DeepEqual(b.Constraints, expected) {
+		t.Errorf("expected %v - got %v instead", expected, b.Constraints)
+	}
+	data, _ := json.Marshal(&b)
+	if !strings.Contains(string(data), text[1:len(text)-1]) {
+		t.Errorf("expected the thermometers, arrows and sandwiches in %s", data)
+	}
+
+	for _, test := range []struct{ text, expected string }{
+		{`{"thermometers":[[0]]}`, "thermometer 1 has 1 cells - expected 2 to 9"},
+		{`{"thermometers":[[0,1],[5,7]]}`, "thermometer 2 jumps from r1c6 to r1c8"},
+		{`{"arrows":[{"circle":0,"cells":[]}]}`, "arrow 1 has no cells"},
+		{`{"arrows":[{"circle":0,"cells":[1,0]}]}`, "arrow 1 has r1c1 twice"},
+		{`{"sandwiches":{"rows":[36]}}`, "the sandwich clue for row 1 is 36 - expected -1 for none or 0 to 35"},
+	} {
+		if err := json.Unmarshal([]byte(test.text), &b); err == nil || err.Error() != test.expected {
+			t.Errorf("%s: expected %s - got %v", test.text, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package solver
+
+// hardPuzzles is a corpus of well-known puzzles that are difficult for a
+// naive backtracker.
+var hardPuzzles = []string{
+	// Platinum Blonde
+	"000000012000000003002300400001800005060070800000009000008500000900040500470006000",
+	// AI Escargot
+	"100007090030020008009600500005300900010080002600004000300000010040000007007000300",
+	// Golden Nugget
+	"000000039000001005003050800008090006070002000100400000009080050020000600400700000",
+	// Easter Monster
+	"100000002090400050006000700050903000000070000000850040700000600030009080002000001",
+	// Arto Inkala, 2012
+	"800000000003600000070090200050007000000045700000100030001000068008500010090000400",
+}
+
+// easyPuzzles is a corpus of puzzles which can be solved with singles alone.
+var easyPuzzles = []string{
+	"003020600900305001001806400008102900700000008006708200002609500800203009005010300",
+	"200080300060070084030500209000105408000000000402706000301007040720040060004010003",
+	"000000907000420180000705026100904000050000040000507009920108000034059000507000000",
+}
+
+// techniquePuzzles is a corpus of puzzles which between them need every
+// technique used by Explain.
+var techniquePuzzles = []string{
+	// pointing, claiming, naked pair, naked triple and simple colouring
+	"200650000100200000600047005010000080008700306000000100000006090000004007074503002",
+	// hidden pair
+	"050790000230000000000000100000060090007010203008000005310500000060001052000680000",
+	// hidden triple
+	"040060180003008009000050030200000700300090402780000056000601000067000000000040000",
+	// X-Wing
+	"000000600005007009070340025300000000050100030709600400001030900000006002000080700",
+	// Swordfish
+	"000003900130280007000100000003060708006090000008300400000500800005001072907000010",
+	// XY-Wing
+	"000000047004070060009400003010007609000008000090203010340000000508902700000000000",
+}
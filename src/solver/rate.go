@@ -0,0 +1,163 @@
+package solver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Difficulty is a difficulty band for puzzles.
+type Difficulty int
+
+// Difficulty bands, from easiest to hardest.
+const (
+	Easy Difficulty = iota
+	Medium
+	Hard
+	Fiendish
+	Diabolical
+)
+
+var difficultyNames = []string{"Easy", "Medium", "Hard", "Fiendish", "Diabolical"}
+
+// the highest rating in each band - anything above Fiendish is Diabolical.
+// The bands follow the groups of the Sudoku Explainer scale: Easy puzzles
+// need only hidden singles, Medium ones naked singles and locked candidates
+// (up to 2.8), Hard ones subsets and basic fish (up to 3.9) and Fiendish
+// ones wings and the like.
+var difficultyLimits = []float64{1.5, 2.8, 3.9, 5.9}
+
+func (d Difficulty) String() string {
+	if d < Easy || d > Diabolical {
+		return fmt.Sprintf("Difficulty(%d)", int(d))
+	}
+	return difficultyNames[d]
+}
+
+// MarshalText encodes the difficulty by name.
+func (d Difficulty) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a difficulty from its name.
+func (d *Difficulty) UnmarshalText(text []byte) error {
+	parsed, err := ParseDifficulty(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// ParseDifficulty returns the Difficulty with the given name, ignoring case.
+func ParseDifficulty(name string) (Difficulty, error) {
+	for d, difficultyName := range difficultyNames {
+		if strings.EqualFold(name, difficultyName) {
+			return Difficulty(d), nil
+		}
+	}
+	return Easy, fmt.Errorf("unknown difficulty %s", name)
+}
+
+func difficultyOf(rating float64) Difficulty {
+	for d, limit := range difficultyLimits {
+		if rating <= limit {
+			return Difficulty(d)
+		}
+	}
+	return Diabolical
+}
+
+// Rating describes how hard a puzzle is to solve by logic.
+type Rating struct {
+	// Value is comparable to the Sudoku Explainer rating - the rating of
+	// the hardest technique needed.
+	Value float64 `json:"value"`
+	// HardestTechnique is the name of the hardest technique needed.
+	HardestTechnique string     `json:"hardestTechnique"`
+	Difficulty       Difficulty `json:"difficulty"`
+	// Complete is false if the puzzle needs techniques beyond those the
+	// rater knows. The puzzle is then Diabolical, Value is at least past
+	// the Fiendish band and its Sudoku Explainer rating is higher still.
+	Complete bool `json:"complete"`
+}
+
+func (r Rating) String() string {
+	s := fmt.Sprintf("%.1f %s (%s)", r.Value, r.Difficulty, r.HardestTechnique)
+	if !r.Complete {
+		s += " - needs harder techniques"
+	}
+	return s
+}
+
+// rateTechniques are the techniques used by Rate, in order of increasing
+// Sudoku Explainer rating, so that every step uses the easiest technique
+// available.
+var rateTechniques = []technique{
+	hiddenSingle,
+	nakedSingle,
+	pointing,
+	claiming,
+	nakedPair,
+	xWing,
+	hiddenPair,
+	nakedTriple,
+	swordfish,
+	hiddenTriple,
+	xyWing,
+	simpleColouring,
+}
+
+// Sudoku Explainer ratings for each technique. Simple colouring does not
+// appear in Sudoku Explainer - it is rated as the equivalent X-Chain.
+var techniqueRatings = map[string]float64{
+	NakedSingle:     2.3,
+	Pointing:        2.6,
+	Claiming:        2.8,
+	NakedPair:       3.0,
+	XWing:           3.2,
+	HiddenPair:      3.4,
+	NakedTriple:     3.6,
+	Swordfish:       3.8,
+	HiddenTriple:    4.0,
+	XYWing:          4.2,
+	SimpleColouring: 6.6,
+}
+
+func stepRating(step Step) float64 {
+	if step.Technique == HiddenSingle {
+		if step.unit == boxUnit {
+			return 1.2
+		}
+		return 1.5
+	}
+	return techniqueRatings[step.Technique]
+}
+
+// Rate solves the puzzle by logic, always using the easiest technique
+// available, and rates it by the hardest technique it needed. Returns the
+// error from Validate if the grid is invalid, and an error if the puzzle
+// doesn't have a unique solution.
+func (grid Grid) Rate() (Rating, error) {
+	explanation, err := grid.explain(rateTechniques)
+	if err != nil {
+		return Rating{}, err
+	}
+	if !grid.HasUniqueSolution() {
+		return Rating{}, fmt.Errorf("the puzzle does not have a unique solution")
+	}
+	// a puzzle which is already solved needs no techniques at all
+	rating := Rating{Value: 1.0, HardestTechnique: "None", Complete: explanation.Solved}
+	for _, step := range explanation.Steps {
+		if value := stepRating(step); value > rating.Value {
+			rating.Value = value
+			rating.HardestTechnique = step.Technique
+		}
+	}
+	// a puzzle the rater can't finish is harder than anything it can, so
+	// its value goes past the Fiendish band to agree with its difficulty
+	if fiendish := difficultyLimits[Fiendish]; !rating.Complete && rating.Value <= fiendish {
+		rating.Value = fiendish + 0.1
+	}
+	rating.Difficulty = difficultyOf(rating.Value)
+	return rating, nil
+}
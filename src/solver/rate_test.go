@@ -0,0 +1,78 @@
+package solver
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRate(t *testing.T) {
+	tables := []struct {
+		puzzle     string
+		value      float64
+		technique  string
+		difficulty Difficulty
+		complete   bool
+	}{
+		{easyPuzzles[0], 1.2, HiddenSingle, Easy, true},
+		{"000300050200168009089000003003500800000780001090010040001670000000900700000000360", 1.5, HiddenSingle, Easy, true},
+		{"400000900020080000580000004004000008300090000600700035730620000002970050005001300", 2.3, NakedSingle, Medium, true},
+		{"002800040000069000680007105200470800450090000000000050001000063800901007030000080", 2.6, Pointing, Medium, true},
+		{"810000030000107509000009020000040090056000000090003400301005000072900005000000300", 3.2, XWing, Hard, true},
+		{"150400700046080025000000300210000800000800200007030060004000000608900001070350000", 3.6, NakedTriple, Hard, true},
+		{"030005009450170008009000006100456000040000002000000300010000080000040000970003500", 4.0, HiddenTriple, Fiendish, true},
+		{"010007325030056780070000000120000600000000208609000000040860000005920004002000003", 4.2, XYWing, Fiendish, true},
+		{"000000700230008500007049038103005000000000016600803000005000900090020003000030001", 6.6, SimpleColouring, Diabolical, true},
+		{hardPuzzles[1], 0, "", Diabolical, false},
+	}
+	for _, table := range tables {
+		grid, _ := NewGridFromString(table.puzzle)
+		rating, err := grid.Rate()
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", table.puzzle, err)
+		}
+		if rating.Difficulty != table.difficulty || rating.Complete != table.complete {
+			t.Errorf("expected %s to be rated %v (complete %v) - got %v instead", table.puzzle, table.difficulty, table.complete, rating)
+		}
+		if table.complete && (rating.Value != table.value || rating.HardestTechnique != table.technique) {
+			t.Errorf("expected %s to be rated %.1f with %s - got %v instead", table.puzzle, table.value, table.technique, rating)
+		}
+		if difficultyOf(rating.Value) != rating.Difficulty {
+			t.Errorf("expected the value of %v to be in its band", rating)
+		}
+	}
+}
+
+func TestRateNotUnique(t *testing.T) {
+	var empty Grid
+	single := empty
+	single[40] = 5
+	for _, grid := range []Grid{empty, single} {
+		if rating, err := grid.Rate(); err == nil {
+			t.Errorf("expected an error rating %s - got %v instead", grid, rating)
+		}
+	}
+}
+
+func TestRateSteps(t *testing.T) {
+	for _, s := range techniquePuzzles {
+		grid, _ := NewGridFromString(s)
+		explanation, _ := grid.explain(rateTechniques)
+		checkSteps(t, s, explanation.Steps)
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	for d := Easy; d <= Diabolical; d++ {
+		parsed, err := ParseDifficulty(d.String())
+		if err != nil || parsed != d {
+			t.Errorf("could not parse %v: %v", d, err)
+		}
+	}
+	if _, err := ParseDifficulty("impossible"); err == nil {
+		t.Error("expected an error for an unknown difficulty")
+	}
+	b, _ := json.Marshal(Rating{Difficulty: Hard})
+	if string(b) != `{"value":0,"hardestTechnique":"","difficulty":"Hard","complete":false}` {
+		t.Errorf("unexpected JSON %s", b)
+	}
+}
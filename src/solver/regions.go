@@ -0,0 +1,99 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ParseRegions parses the layout of a jigsaw board. The layout has one
+// character per cell, row by row, with the same character for every cell in
+// a region - such as 111222333 for the top row of a board whose top three
+// regions are side by side. Separators, whitespace and line breaks are
+// ignored. Regions are numbered in the order they first appear.
+func ParseRegions(s string) ([]int, error) {
+	labels := make(map[rune]int)
+	var regions []int
+	for _, r := range s {
+		if isSeparator(r) || r == '\n' {
+			continue
+		}
+		if _, ok := labels[r]; !ok {
+			labels[r] = len(labels)
+		}
+		regions = append(regions, labels[r])
+	}
+	size := int(math.Sqrt(float64(len(regions))))
+	if size*size != len(regions) || size == 0 {
+		return nil, fmt.Errorf("layout has %d cells, which do not make a square board", len(regions))
+	}
+	if err := checkRegions(regions, size); err != nil {
+		return nil, err
+	}
+	return regions, nil
+}
+
+// RegionsString returns the layout of a jigsaw board in the form accepted by
+// ParseRegions, using the symbols 1 to 9 then A to P.
+func RegionsString(regions []int) string {
+	var b strings.Builder
+	for _, region := range regions {
+		b.WriteByte(symbol(region + 1))
+	}
+	return b.String()
+}
+
+// checkRegions returns an error unless regions splits a board with size rows
+// into size connected regions of size cells each.
+func checkRegions(regions []int, size int) error {
+	if size < 1 || size > MaxBoardSize {
+		return fmt.Errorf("board size %d is not from 1 to %d", size, MaxBoardSize)
+	}
+	if len(regions) != size*size {
+		return fmt.Errorf("expected a region for each of %d cells - got %d", size*size, len(regions))
+	}
+	counts := make([]int, size)
+	first := make([]int, size)
+	for i, region := range regions {
+		if region < 0 || region >= size {
+			return fmt.Errorf("%s is in region %d - expected 1 to %d", cellNameOn(i, size), region+1, size)
+		}
+		if counts[region] == 0 {
+			first[region] = i
+		}
+		counts[region]++
+	}
+	for region, count := range counts {
+		if count != size {
+			return fmt.Errorf("region %d has %d cells - expected %d", region+1, count, size)
+		}
+	}
+
+	// flood fill each region from its first cell
+	reached := make([]bool, len(regions))
+	for region := range counts {
+		stack := []int{first[region]}
+		reached[first[region]] = true
+		n := 0
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			n++
+			row, column := i/size, i%size
+			for _, next := range [][2]int{{row - 1, column}, {row + 1, column}, {row, column - 1}, {row, column + 1}} {
+				if next[0] < 0 || next[0] >= size || next[1] < 0 || next[1] >= size {
+					continue
+				}
+				j := next[0]*size + next[1]
+				if regions[j] == region && !reached[j] {
+					reached[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		if n != size {
+			return fmt.Errorf("region %d is split into pieces", region+1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package solver
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	jigsawLayout   = "111122333112122333112222633444556663444556566774855566774885999774888999777888999"
+	jigsawPuzzle   = "089000040000050000000000008004000000300000000000000080031500004800002001010040609"
+	jigsawSolution = "789316245463258917152497368524681793398724156976135482631579824847962531215843679"
+)
+
+func TestJigsawSolve(t *testing.T) {
+	b, err := ParseJigsaw(jigsawPuzzle, jigsawLayout)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if count, err := b.CountSolutions(context.Background(), 0); err != nil || count != 1 {
+		t.Errorf("expected a unique solution - got %d, %v", count, err)
+	}
+	if _, err := b.Grid(); err == nil {
+		t.Error("expected an error converting a jigsaw board to a Grid")
+	}
+	if err := b.Solve(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if b.String() != jigsawSolution {
+		t.Errorf("expected %s - got %s instead", jigsawSolution, b)
+	}
+	// the solution breaks the usual boxes
+	grid, _ := NewGridFromString(jigsawSolution)
+	if grid.Validate() == nil {
+		t.Error("expected the jigsaw solution to be invalid as a Grid")
+	}
+
+	var buf bytes.Buffer
+	b.Print(&buf)
+	if !strings.HasPrefix(buf.String(), "789316245\n463258917\n") {
+		t.Errorf("unexpected output\n%s", buf.String())
+	}
+}
+
+func TestJigsawValidate(t *testing.T) {
+	b, _ := ParseJigsaw(jigsawPuzzle, jigsawLayout)
+	// r1c5 is in region 2 with r2c3, which holds nothing yet
+	b.Cells[4], b.Cells[11] = 7, 7
+	err := b.Validate()
+	if err == nil || !strings.Contains(err.Error(), "7 appears twice in region 2, at r1c5 and r2c3") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	b, _ = ParseJigsaw(jigsawPuzzle, jigsawLayout)
+	b.Regions[0] = 1
+	if err := b.Validate(); err == nil {
+		t.Error("expected an error for a board with broken regions")
+	}
+}
+
+func TestJigsawLayout(t *testing.T) {
+	b, _ := ParseJigsaw(jigsawPuzzle, jigsawLayout)
+	l := b.layout()
+	if b.layout() != l || b.Clone().layout() != l {
+		t.Error("expected the layout to be built once")
+	}
+	// boards are checked concurrently by the server
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.Validate(); err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParseRegions(t *testing.T) {
+	regions, err := ParseRegions("AABB|AABB|CCDD|CCDD")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if RegionsString(regions) != "1122112233443344" {
+		t.Errorf("unexpected regions %s", RegionsString(regions))
+	}
+
+	errors := []string{
+		"",
+		"AAB",
+		// region A is split in two
+		"ABBA ABBA CCDD CCDD",
+		// regions of 3 and 5 cells
+		"AAAA ABBB CCDD CCDD",
+	}
+	for _, layout := range errors {
+		if _, err := ParseRegions(layout); err == nil {
+			t.Errorf("%q: expected an error", layout)
+		}
+	}
+
+	// a 5x5 board has no box shape, but can have regions
+	b, err := ParseJigsaw("1....\n.....\n.....\n.....\n.....", "11122\n11222\n33344\n33444\n55555")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := b.Solve(context.Background(), nil); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,476 @@
+package solver
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// SandwichConstraint is the rule that the values between the smallest and
+// largest value in a row or column - 1 and 9 on a 9x9 board - add up to its
+// clue. A clue of 0 means the two are side by side.
+type SandwichConstraint struct {
+	// Rows holds the clue for each row from the top, and Columns for each
+	// column from the left. A clue of -1 means the line has no clue, as do
+	// lines past the end of the list.
+	Rows    []int `json:"rows,omitempty"`
+	Columns []int `json:"columns,omitempty"`
+}
+
+// sandwichLine is a row or column with a sandwich clue.
+type sandwichLine struct {
+	row   bool
+	index int
+	cells []int
+	clue  int
+}
+
+func (l sandwichLine) String() string {
+	if l.row {
+		return fmt.Sprintf("row %d", l.index+1)
+	}
+	return fmt.Sprintf("column %d", l.index+1)
+}
+
+// lines returns the rows and columns of b which have clues.
+func (s SandwichConstraint) lines(b *Board) []sandwichLine {
+	var lines []sandwichLine
+	for r, cells := range (RowConstraint{}).groups(b) {
+		if r < len(s.Rows) && s.Rows[r] >= 0 {
+			lines = append(lines, sandwichLine{true, r, cells, s.Rows[r]})
+		}
+	}
+	for c, cells := range (ColumnConstraint{}).groups(b) {
+		if c < len(s.Columns) && s.Columns[c] >= 0 {
+			lines = append(lines, sandwichLine{false, c, cells, s.Columns[c]})
+		}
+	}
+	return lines
+}
+
+// Forbidden returns the values which would leave no way to fill in the row or
+// column of cell i to make up its clue.
+func (s SandwichConstraint) Forbidden(b *Board, i int) uint32 {
+	var forbidden uint32
+	values := make([]int, b.Size())
+	for _, line := range s.lines(b) {
+		if k := indexOf(line.cells, i); k != -1 {
+			for n, j := range line.cells {
+				values[n] = b.Cells[j]
+			}
+			forbidden |= forbiddenAt(values, k, line.clue, valuesIn(b, line.cells))
+		}
+	}
+	return forbidden
+}
+
+// Check adds a Violation to e for every row or column whose values between 1
+// and the largest value don't add up to its clue, or can't once the line is
+// filled in, and for clues which are out of range.
+func (s SandwichConstraint) Check(b *Board, e *ValidationError) {
+	size := b.Size()
+	if err := s.checkShape(size); err != nil {
+		e.Violations = append(e.Violations, Violation{Rule: "sandwich", Msg: err.Error()})
+		return
+	}
+	values := make([]int, size)
+	for _, line := range s.lines(b) {
+		low, high := -1, -1
+		inRange := true
+		for k, j := range line.cells {
+			values[k] = b.Cells[j]
+			switch {
+			case values[k] < 0 || values[k] > size:
+				inRange = false
+			case values[k] == 1:
+				low = k
+			case values[k] == size:
+				high = k
+			}
+		}
+		if !inRange {
+			continue
+		}
+		if low > high {
+			low, high = high, low
+		}
+		sum, full := 0, low != -1
+		for k := low + 1; full && k < high; k++ {
+			sum += values[k]
+			full = values[k] != 0
+		}
+		var msg string
+		switch {
+		case full && sum != line.clue:
+			msg = fmt.Sprintf("the values between 1 and %c in %s add up to %d, not %d", symbol(size), line, sum, line.clue)
+		case !full && !sandwichFits(values, size, line.clue):
+			msg = fmt.Sprintf("the values between 1 and %c in %s can't add up to %d", symbol(size), line, line.clue)
+		default:
+			continue
+		}
+		e.Violations = append(e.Violations, Violation{
+			Rule:  "sandwich",
+			Cells: append([]int(nil), line.cells...),
+			Msg:   msg,
+		})
+	}
+}
+
+// checkShape returns an error unless there is at most a clue for each row
+// and column of a board with size rows, and every clue is -1 or a sum the
+// values from 2 to size-1 could make.
+func (s SandwichConstraint) checkShape(size int) error {
+	most := size*(size+1)/2 - 1 - size
+	for _, clues := range []struct {
+		name  string
+		clues []int
+	}{{"row", s.Rows}, {"column", s.Columns}} {
+		if len(clues.clues) > size {
+			return fmt.Errorf("there are %d sandwich clues for the %ss - expected at most %d", len(clues.clues), clues.name, size)
+		}
+		for n, clue := range clues.clues {
+			if clue < -1 || clue > most {
+				return fmt.Errorf("the sandwich clue for %s %d is %d - expected -1 for none or 0 to %d", clues.name, n+1, clue, most)
+			}
+		}
+	}
+	return nil
+}
+
+// prepare finds the lines with clues, and the places of each cell in them,
+// once for a search.
+func (s SandwichConstraint) prepare(b *Board) Constraint {
+	p := &preparedSandwich{
+		SandwichConstraint: s,
+		lines:              s.lines(b),
+		places:             make([][]sandwichPlace, len(b.Cells)),
+	}
+	for n, line := range p.lines {
+		for k, i := range line.cells {
+			p.places[i] = append(p.places[i], sandwichPlace{n, k})
+		}
+		seen := make([]int, len(line.cells))
+		for k := range seen {
+			seen[k] = -1
+		}
+		p.seen = append(p.seen, seen)
+		p.forbidden = append(p.forbidden, make([]uint32, len(line.cells)))
+	}
+	return p
+}
+
+// preparedSandwich is a SandwichConstraint for one shape of board. A search
+// only changes a cell at a time, so it keeps the values each line forbids
+// until the line changes. It also narrows down the candidates the search
+// finds, which Forbidden alone can't, as it only sees the values on the
+// board.
+type preparedSandwich struct {
+	SandwichConstraint
+	lines []sandwichLine
+	// places lists where each cell is in the lines with clues
+	places [][]sandwichPlace
+	// seen holds the values in each line when the values forbidden in its
+	// cells were last worked out, and forbidden holds them
+	seen      [][]int
+	forbidden [][]uint32
+	// sums holds recent answers from canAddUp
+	sums [1 << 12]sumAnswer
+}
+
+// sandwichPlace is the place of a cell in a line, as index k of lines[line].
+type sandwichPlace struct {
+	line, k int
+}
+
+// sumAnswer is whether some values can add up to a sum, with key packing
+// together the values, how many to use and the sum.
+type sumAnswer struct {
+	key uint64
+	ok  bool
+}
+
+func (p *preparedSandwich) Forbidden(b *Board, i int) uint32 {
+	var forbidden uint32
+	for _, place := range p.places[i] {
+		if !p.current(b, place.line) {
+			p.update(b, place.line)
+		}
+		forbidden |= p.forbidden[place.line][place.k]
+	}
+	return forbidden
+}
+
+// current reports whether line n holds the values it did when the values
+// forbidden in its cells were worked out.
+func (p *preparedSandwich) current(b *Board, n int) bool {
+	seen := p.seen[n]
+	for k, i := range p.lines[n].cells {
+		if b.Cells[i] != seen[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// update works out the values forbidden in each cell of line n.
+func (p *preparedSandwich) update(b *Board, n int) {
+	line, values := p.lines[n], p.seen[n]
+	for k, i := range line.cells {
+		values[k] = b.Cells[i]
+	}
+	used := valuesIn(b, line.cells)
+	for k := range values {
+		p.forbidden[n][k] = forbiddenAt(values, k, line.clue, used)
+	}
+}
+
+// narrow only keeps 1 and the largest value in the cells of each line where
+// they could both go with a sandwich between them, given the candidates of
+// every cell, and only keeps the values the other cells could hold with them
+// there.
+func (p *preparedSandwich) narrow(b *Board, candidates []uint32) bool {
+	size := b.Size()
+	low, high := uint32(1), uint32(1)<<uint(size-1)
+	ends := low | high
+	for _, line := range p.lines {
+		var masks, allowed [MaxBoardSize]uint32
+		for k, i := range line.cells {
+			if value := b.Cells[i]; value != 0 {
+				masks[k] = 1 << uint(value-1)
+			} else {
+				masks[k] = candidates[i]
+			}
+		}
+		for l := range line.cells {
+			if masks[l]&low == 0 {
+				continue
+			}
+			for h := range line.cells {
+				if h == l || masks[h]&high == 0 {
+					continue
+				}
+				from, to := l, h
+				if from > to {
+					from, to = to, from
+				}
+				inside, outside, ok := p.fits(masks[:len(line.cells)], from, to, size, line.clue)
+				if !ok {
+					continue
+				}
+				allowed[l] |= low
+				allowed[h] |= high
+				for k := range line.cells {
+					mask := masks[k] &^ ends
+					switch {
+					case k == l || k == h:
+					case mask&(mask-1) == 0:
+						allowed[k] |= mask
+					case k > from && k < to:
+						allowed[k] |= mask & inside
+					default:
+						allowed[k] |= mask & outside
+					}
+				}
+			}
+		}
+		for k, i := range line.cells {
+			if b.Cells[i] != 0 {
+				continue
+			}
+			if candidates[i] &= allowed[k]; candidates[i] == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// fits reports whether the cells of a line, which could hold masks, leave
+// room for a sandwich between from and to adding up to clue, with no other
+// cell holding 1 or size. The cells outside the sandwich hold the rest of
+// the values from 2 to size-1, so they must add up to the rest of their
+// total. If so it returns the values which the cells inside and outside
+// which aren't already settled could hold.
+func (p *preparedSandwich) fits(masks []uint32, from, to, size, clue int) (inside, outside uint32, ok bool) {
+	ends := uint32(1) | 1<<uint(size-1)
+	var sums, counts [2]int
+	var unions [2]uint32
+	for k, mask := range masks {
+		if k == from || k == to {
+			continue
+		}
+		mask &^= ends
+		side := 0
+		if k < from || k > to {
+			side = 1
+		}
+		switch {
+		case mask == 0:
+			return 0, 0, false
+		case mask&(mask-1) == 0:
+			sums[side] += bits.TrailingZeros32(mask) + 1
+		default:
+			counts[side]++
+			unions[side] |= mask
+		}
+	}
+	rest := size*(size+1)/2 - 1 - size - clue
+	wants := [2]int{clue - sums[0], rest - sums[1]}
+	var values [2]uint32
+	for side := range values {
+		if !p.canAddUp(unions[side], counts[side], wants[side]) {
+			return 0, 0, false
+		}
+		for m := unions[side]; m != 0; m &= m - 1 {
+			bit := m & -m
+			v := bits.TrailingZeros32(m) + 1
+			if p.canAddUp(unions[side]&^bit, counts[side]-1, wants[side]-v) {
+				values[side] |= bit
+			}
+		}
+	}
+	return values[0], values[1], true
+}
+
+// canAddUp is canAddUp keeping recent answers, as a search asks the same
+// questions again and again.
+func (p *preparedSandwich) canAddUp(set uint32, count, sum int) bool {
+	if count == 0 || sum < 0 {
+		return count == 0 && sum == 0
+	}
+	// add 1 so that no key is 0, which marks an unused answer
+	key := uint64(set)<<32 | uint64(count)<<16 | uint64(sum) + 1
+	answer := &p.sums[(key*0x9e3779b97f4a7c15)>>52]
+	if answer.key != key {
+		*answer = sumAnswer{key, canAddUp(set, count, sum)}
+	}
+	return answer.ok
+}
+
+// forbiddenAt returns the values which would leave no way to fill in the rest
+// of a row or column holding values, which already holds used, if put at
+// index k. values is left as it was.
+func forbiddenAt(values []int, k, clue int, used uint32) uint32 {
+	size := len(values)
+	value := values[k]
+	var forbidden uint32
+	for v := 1; v <= size; v++ {
+		// the row or column rules rule out values already in the line
+		if used&(1<<uint(v-1)) != 0 {
+			continue
+		}
+		values[k] = v
+		if !sandwichFits(values, size, clue) {
+			forbidden |= 1 << uint(v-1)
+		}
+	}
+	values[k] = value
+	return forbidden
+}
+
+// sandwichFits reports whether the empty cells of a row or column holding
+// values could be filled in with the values it is missing so that the values
+// between 1 and size add up to clue. Other rules are ignored.
+func sandwichFits(values []int, size, clue int) bool {
+	all := uint32(1)<<uint(size) - 1
+	missing := all
+	low, high := -1, -1
+	// sums[k] and gaps[k] are the total of the values and the number of
+	// empty cells before index k
+	var sums, gaps [MaxBoardSize + 1]int
+	for k, value := range values {
+		sums[k+1], gaps[k+1] = sums[k]+value, gaps[k]
+		switch {
+		case value == 0:
+			gaps[k+1]++
+		case value == 1:
+			low = k
+		case value == size:
+			high = k
+		}
+		if value > 0 {
+			missing &^= 1 << uint(value-1)
+		}
+	}
+	// try every place left for 1 and for size
+	inner := missing &^ (1 | 1<<uint(size-1))
+	reachable, small := sumsOf(inner)
+	for l, lv := range values {
+		if l != low && (low != -1 || lv != 0) {
+			continue
+		}
+		for h, hv := range values {
+			if h == l || h != high && (high != -1 || hv != 0) {
+				continue
+			}
+			from, to := l, h
+			if from > to {
+				from, to = to, from
+			}
+			sum, count := sums[to]-sums[from+1], gaps[to]-gaps[from+1]
+			if small {
+				if want := clue - sum; want >= 0 && want < 64 && reachable[count]&(1<<uint(want)) != 0 {
+					return true
+				}
+			} else if canAddUp(inner, count, clue-sum) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sumsOf returns the sums which different values from set could add up to,
+// with value v in bit v-1 of set, as a bit for each sum for each number of
+// values. ok is false if the values in set add up to 64 or more, as they can
+// on boards larger than 11x11, which leaves canAddUp to work them out.
+func sumsOf(set uint32) (sums [MaxBoardSize + 1]uint64, ok bool) {
+	total := 0
+	for m := set; m != 0; m &= m - 1 {
+		total += bits.TrailingZeros32(m) + 1
+	}
+	if total >= 64 {
+		return sums, false
+	}
+	sums[0] = 1
+	count := 0
+	for m := set; m != 0; m &= m - 1 {
+		v := uint(bits.TrailingZeros32(m) + 1)
+		count++
+		for c := count; c > 0; c-- {
+			sums[c] |= sums[c-1] << v
+		}
+	}
+	return sums, true
+}
+
+// canAddUp reports whether count of the values in set, with value v in bit
+// v-1, add up to sum.
+func canAddUp(set uint32, count, sum int) bool {
+	if count == 0 {
+		return sum == 0
+	}
+	if bits.OnesCount32(set) < count {
+		return false
+	}
+	// the smallest and largest values set could make
+	low, high := 0, 0
+	for m, k := set, 0; k < count; k++ {
+		low += bits.TrailingZeros32(m) + 1
+		m &= m - 1
+	}
+	for m, k := set, 0; k < count; k++ {
+		top := 31 - bits.LeadingZeros32(m)
+		high += top + 1
+		m &^= 1 << uint(top)
+	}
+	if sum < low || sum > high {
+		return false
+	}
+	for m := set; m != 0; m &= m - 1 {
+		v := bits.TrailingZeros32(m) + 1
+		if canAddUp(m&(m-1), count-1, sum-v) {
+			return true
+		}
+	}
+	return false
+}
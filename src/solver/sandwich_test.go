@@ -0,0 +1,116 @@
+package solver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSandwichSolve(t *testing.T) {
+	b, _ := ParseBoard("000008000000105000000000000050000000000000001000000800000000000007400000300000000", 0, 0)
+	if count, err := b.CountSolutions(context.Background(), 2); err != nil || count != 2 {
+		t.Errorf("expected more than one solution without the clues - got %d, %v", count, err)
+	}
+	b.Constraints = []Constraint{SandwichConstraint{
+		Rows:    []int{0, 0, 0, 13, 0, 3, 6, 0, 7},
+		Columns: []int{19, 7, 9, 18, 20, 14, 35, 12, 15},
+	}}
+	if count, err := b.CountSolutions(context.Background(), 0); err != nil || count != 1 {
+		t.Errorf("expected a unique solution - got %d, %v", count, err)
+	}
+	if err := b.Solve(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if b.String() != killerSolution {
+		t.Errorf("expected %s - got %s instead", killerSolution, b)
+	}
+}
+
+func TestSandwichSolveFewGivens(t *testing.T) {
+	// a clue for every column but only every other row, and seven givens
+	b, _ := ParseBoard("000005000000810050000000000000000000000000000500030000000000000000040000000000000", 0, 0)
+	b.Constraints = []Constraint{SandwichConstraint{
+		Rows:    []int{-1, 2, -1, 9, -1, 0, -1, 25, -1},
+		Columns: []int{25, 0, 23, 0, 0, 7, 11, 17, 27},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := b.Clone().Solve(ctx, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if count, err := b.CountSolutions(context.Background(), 2); err != nil || count != 1 {
+		t.Errorf("expected a unique solution - got %d, %v", count, err)
+	}
+}
+
+func TestSandwichPrepare(t *testing.T) {
+	b, _ := ParseBoard("000000000000000000000000000000000000000000000000000000000000000000000000000000000", 0, 0)
+	s := SandwichConstraint{Rows: []int{35, 0}, Columns: []int{-1, 10}}
+	p := s.prepare(b).(*preparedSandwich)
+	b.Cells[10], b.Cells[14] = 9, 2
+	for i := range b.Cells {
+		if expected, forbidden := s.Forbidden(b, i), p.Forbidden(b, i); forbidden != expected {
+			t.Errorf("expected %#x to be forbidden at %s - got %#x instead", expected, cellName(i), forbidden)
+		}
+	}
+
+	// 1 and 9 have to be at the ends of the first row
+	b.Cells[10], b.Cells[14] = 0, 0
+	candidates := make([]uint32, len(b.Cells))
+	for i := range candidates {
+		candidates[i] = 0x1ff
+	}
+	if !p.narrow(b, candidates) {
+		t.Fatal("expected candidates to be left in every cell")
+	}
+	if candidates[0] != 0x101 || candidates[8] != 0x101 || candidates[4] != 0xfe {
+		t.Errorf("expected 1 and 9 to be left at the ends of row 1 - got %#x, %#x and %#x", candidates[0], candidates[4], candidates[8])
+	}
+}
+
+func TestSandwichFits(t *testing.T) {
+	tests := []struct {
+		values   []int
+		clue     int
+		expected bool
+	}{
+		{[]int{1, 9, 0, 0, 0, 0, 0, 0, 0}, 0, true},
+		{[]int{1, 9, 0, 0, 0, 0, 0, 0, 0}, 5, false},
+		{[]int{0, 0, 0, 0, 0, 0, 0, 0, 0}, 35, true},
+		{[]int{0, 0, 0, 0, 0, 0, 0, 0, 0}, 0, true},
+		{[]int{1, 0, 9, 0, 0, 0, 0, 0, 0}, 2, true},
+		{[]int{1, 0, 9, 0, 0, 0, 0, 0, 0}, 9, false},
+		// 9 can go at the far end, past the 8 and 7
+		{[]int{0, 1, 8, 7, 0, 0, 0, 0, 0}, 15, true},
+		{[]int{0, 1, 8, 7, 0, 0, 0, 0, 0}, 16, false},
+	}
+	for _, test := range tests {
+		if fits := sandwichFits(test.values, 9, test.clue); fits != test.expected {
+			t.Errorf("%v with clue %d: expected %t - got %t", test.values, test.clue, test.expected, fits)
+		}
+	}
+	if !canAddUp(0xe, 2, 7) || !canAddUp(0xe, 2, 5) || canAddUp(0xe, 2, 8) {
+		t.Error("expected two of 2, 3 and 4 to add up to 5 or 7 but not 8")
+	}
+}
+
+func TestSandwichValidate(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	b.Constraints = []Constraint{SandwichConstraint{Rows: []int{4, -1, 0}, Columns: []int{35}}}
+	b.Cells[0], b.Cells[1], b.Cells[2] = 1, 2, 9
+	b.Cells[18], b.Cells[19] = 1, 5
+	err := b.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError - got %v", err)
+	}
+	expected := []Violation{
+		{"sandwich", []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, "the values between 1 and 9 in row 1 add up to 2, not 4"},
+		{"sandwich", []int{18, 19, 20, 21, 22, 23, 24, 25, 26}, "the values between 1 and 9 in row 3 can't add up to 0"},
+		{"sandwich", []int{0, 9, 18, 27, 36, 45, 54, 63, 72}, "the values between 1 and 9 in column 1 can't add up to 35"},
+	}
+	if !reflect.DeepEqual(verr.Violations, expected) {
+		t.Errorf("expected %v - got %v instead", expected, verr.Violations)
+	}
+}
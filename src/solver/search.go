@@ -0,0 +1,209 @@
+package solver
+
+// search holds the state of a backtracking search over a grid.
+type search struct {
+	canceller
+
+	grid     *Grid
+	ch       chan UpdateEvent
+	options  Options
+	occupied occupancy
+
+	// the grid before the search started, restored if the search is
+	// cancelled
+	start Grid
+
+	// trail holds the cells filled in by propagation, in the order they
+	// were filled, so that they can be undone when backtracking
+	trail []int
+
+	// branches counts the cells the search had to guess on
+	branches int
+}
+
+func newSearch(grid *Grid, ch chan UpdateEvent, options Options) *search {
+	return &search{
+		grid:     grid,
+		ch:       ch,
+		options:  options,
+		occupied: newOccupancy(grid),
+		start:    *grid,
+		trail:    make([]int, 0, 81),
+	}
+}
+
+func (s *search) solve() bool {
+	return s.run(func() bool { return false })
+}
+
+// run searches for solutions, calling found with the grid filled in for each
+// solution. The search carries on looking for more solutions while found
+// returns true. Returns true if the search stopped at a solution, leaving it
+// in the grid, or false if the search ran out of solutions or was cancelled,
+// leaving the grid as it was at the start.
+func (s *search) run(found func() bool) bool {
+	if s.options.Propagate && !s.propagate() {
+		s.undo(0)
+		return false
+	}
+	index, candidates := s.grid.nextCell(&s.occupied, s.options.Order, 0)
+	if index == -1 {
+		if !found() {
+			return true
+		}
+		s.undo(0)
+		return false
+	}
+	if candidates == 0 {
+		s.undo(0)
+		return false
+	}
+
+	st := newStack()
+	st.push(s.newCellContext(index, candidates))
+	var context *cellcontext
+
+	for st.hasMore() {
+		if s.stop() {
+			*s.grid = s.start
+			return false
+		}
+		context, _ = st.peek()
+		if context.hasMoreCandidates() {
+			// clear the candidate we tried previously, if any
+			s.reset(context)
+			candidate := context.nextCandidate()
+			s.set(context.index, candidate)
+			if s.options.Propagate && !s.propagate() {
+				// contradiction - move on to the next candidate for this cell
+				continue
+			}
+			next, candidates := s.grid.nextCell(&s.occupied, s.options.Order, context.index+1)
+			if next == -1 {
+				if !found() {
+					st.pop()
+					return true
+				}
+				// carry on searching from the next candidate for this cell
+				continue
+			}
+			if candidates == 0 {
+				// dead end - move on to the next candidate for this cell
+				continue
+			}
+			st.push(s.newCellContext(next, candidates))
+		} else {
+			// unsuccessful - so we'll reset the cell to empty
+			s.reset(context)
+			st.pop()
+		}
+	}
+
+	s.undo(0)
+	return false
+}
+
+func (s *search) newCellContext(index int, candidates digitMask) cellcontext {
+	s.branches++
+	c := newCellContext(index, candidates)
+	c.trail = len(s.trail)
+	return c
+}
+
+// set places a digit in a cell and notifies the update channel.
+func (s *search) set(index, digit int) {
+	if previous := s.grid[index]; previous != 0 {
+		s.occupied.remove(index, previous)
+	}
+	s.grid[index] = digit
+	if digit != 0 {
+		s.occupied.place(index, digit)
+	}
+	if s.ch != nil {
+		s.send(s.ch, UpdateEvent{Index: index, Value: digit})
+	}
+}
+
+// reset empties the cell of a context along with every cell propagation
+// filled in after it.
+func (s *search) reset(context *cellcontext) {
+	if s.grid[context.index] == 0 {
+		return
+	}
+	s.undo(context.trail)
+	s.set(context.index, 0)
+}
+
+// undo empties the cells on the trail till it is of length mark.
+func (s *search) undo(mark int) {
+	for len(s.trail) > mark {
+		last := len(s.trail) - 1
+		s.set(s.trail[last], 0)
+		s.trail = s.trail[:last]
+	}
+}
+
+// deduce fills in a cell during propagation.
+func (s *search) deduce(index, digit int) {
+	s.set(index, digit)
+	s.trail = append(s.trail, index)
+}
+
+// propagate repeatedly fills in naked and hidden singles till no more can be
+// found. Returns false if the grid has reached a contradiction.
+func (s *search) propagate() bool {
+	grid := s.grid
+	for progress := true; progress; {
+		progress = false
+
+		// naked singles - cells with a single candidate
+		for i := 0; i < 81; i++ {
+			if grid[i] != 0 {
+				continue
+			}
+			candidates := s.occupied.candidates(i)
+			switch candidates.count() {
+			case 0:
+				return false
+			case 1:
+				s.deduce(i, candidates.lowest())
+				progress = true
+			}
+		}
+
+		// hidden singles - digits with a single possible cell in a unit
+		for u := range units {
+			var once, twice, placed digitMask
+			for _, i := range units[u] {
+				if grid[i] != 0 {
+					placed |= maskOf(grid[i])
+					continue
+				}
+				candidates := s.occupied.candidates(i)
+				twice |= once & candidates
+				once |= candidates
+			}
+			if once|placed != allDigits {
+				// a digit has nowhere to go in this unit
+				return false
+			}
+			for singles := once &^ twice &^ placed; singles != 0; singles &= singles - 1 {
+				digit := singles.lowest()
+				index := -1
+				for _, i := range units[u] {
+					if grid[i] == 0 && s.occupied.candidates(i).has(digit) {
+						index = i
+						break
+					}
+				}
+				if index == -1 {
+					// the cell was taken by another hidden single
+					return false
+				}
+				s.deduce(index, digit)
+				progress = true
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package solver
+
+import "testing"
+
+func TestPropagateWithoutGuessing(t *testing.T) {
+	for _, s := range easyPuzzles {
+		grid, _ := NewGridFromString(s)
+		search := newSearch(&grid, nil, Options{Propagate: true})
+		if !search.solve() {
+			t.Errorf("did not manage to solve %s", s)
+			continue
+		}
+		if search.branches != 0 {
+			t.Errorf("expected %s to be solved without guessing - made %d guesses instead", s, search.branches)
+		}
+	}
+}
+
+func TestPropagateUndo(t *testing.T) {
+	// two 1s in the first row - unsolvable
+	s := "110000000000000000000000000000000000000000000000000000000000000000000000000000000"
+	grid, _ := NewGridFromString(s)
+	if grid.SolveWithOptions(nil, Options{Propagate: true}) {
+		t.Fatal("expected puzzle to be unsolvable")
+	}
+	if grid.String() != s {
+		t.Errorf("expected grid to be restored to %s - got %s instead", s, grid)
+	}
+}
+
+func TestPropagateUpdateEvents(t *testing.T) {
+	grid, _ := NewGridFromString(testPuzzle)
+	ch := make(chan UpdateEvent, 10000)
+	if !grid.SolveWithOptions(ch, Options{Propagate: true}) {
+		t.Fatal("did not manage to solve the puzzle")
+	}
+	close(ch)
+
+	// replaying the events should reproduce the solution
+	replay, _ := NewGridFromString(testPuzzle)
+	for event := range ch {
+		replay[event.Index] = event.Value
+	}
+	if replay != grid {
+		t.Errorf("replayed events produced %s - expected %s", replay, grid)
+	}
+}
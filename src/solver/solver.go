@@ -0,0 +1,39 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+)
+
+// Solver is a Sudoku solving algorithm.
+type Solver interface {
+	// Solve fills in grid, sending every change to ch if it is not nil. It
+	// returns a *ValidationError if the grid is invalid, ErrUnsolvable if
+	// there is no solution, or ErrCancelled or ErrTimedOut if ctx is done
+	// before a solution is found.
+	Solve(ctx context.Context, grid *Grid, ch chan UpdateEvent) error
+}
+
+// Backtracker solves grids with a depth-first search over the candidates for
+// each cell.
+type Backtracker struct {
+	Options Options
+}
+
+// Solve fills in grid, sending every change to ch if it is not nil.
+func (b Backtracker) Solve(ctx context.Context, grid *Grid, ch chan UpdateEvent) error {
+	return grid.SolveContext(ctx, ch, b.Options)
+}
+
+// NewSolver returns the solver for an algorithm - "backtrack" or "dlx". An
+// empty algorithm returns the backtracker. options are ignored by algorithms
+// which do not use them.
+func NewSolver(algorithm string, options Options) (Solver, error) {
+	switch algorithm {
+	case "", "backtrack":
+		return Backtracker{Options: options}, nil
+	case "dlx":
+		return DancingLinks{}, nil
+	}
+	return nil, fmt.Errorf("unknown algorithm %s", algorithm)
+}
@@ -0,0 +1,158 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// mixedPuzzles is a corpus of puzzles of varying difficulty.
+var mixedPuzzles = append(append(append([]string{}, easyPuzzles...), testPuzzle), hardPuzzles...)
+
+var solvers = []struct {
+	name   string
+	solver Solver
+}{
+	{"backtrack", Backtracker{}},
+	{"backtrack-mrv-propagate", Backtracker{Options: Options{Order: MinimumRemaining, Propagate: true}}},
+	{"dlx", DancingLinks{}},
+}
+
+func TestSolvers(t *testing.T) {
+	for _, s := range mixedPuzzles {
+		var expected Grid
+		for i, entry := range solvers {
+			grid, _ := NewGridFromString(s)
+			if err := entry.solver.Solve(context.Background(), &grid, nil); err != nil {
+				t.Errorf("%s did not manage to solve %s: %v", entry.name, s, err)
+				continue
+			}
+			if i == 0 {
+				expected = grid
+			} else if grid != expected {
+				t.Errorf("%s solved %s as %s - expected %s", entry.name, s, grid, expected)
+			}
+		}
+	}
+}
+
+func TestDancingLinksUnsolvable(t *testing.T) {
+	tables := []string{
+		// two 1s in the first row
+		"110000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		// the last cell in the first row has no candidates
+		"123456780000000009000000000000000000000000000000000000000000000000000000000000000",
+	}
+	for _, s := range tables {
+		grid, _ := NewGridFromString(s)
+		if err := (DancingLinks{}).Solve(context.Background(), &grid, nil); err == nil {
+			t.Errorf("expected %s to be unsolvable", s)
+		}
+	}
+}
+
+func TestDancingLinksUpdateEvents(t *testing.T) {
+	grid, _ := NewGridFromString(testPuzzle)
+	ch := make(chan UpdateEvent, 10000)
+	if err := (DancingLinks{}).Solve(context.Background(), &grid, ch); err != nil {
+		t.Fatalf("did not manage to solve the puzzle: %v", err)
+	}
+	close(ch)
+
+	replay, _ := NewGridFromString(testPuzzle)
+	for event := range ch {
+		replay[event.Index] = event.Value
+	}
+	if replay != grid {
+		t.Errorf("replayed events produced %s - expected %s", replay, grid)
+	}
+}
+
+func TestSolveCancelled(t *testing.T) {
+	for _, entry := range solvers {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		grid, _ := NewGridFromString(hardPuzzles[0])
+		// nobody reads the channel, so the solver would block forever if it
+		// did not notice the cancellation
+		err := entry.solver.Solve(ctx, &grid, make(chan UpdateEvent))
+		if err != ErrCancelled {
+			t.Errorf("%s: expected ErrCancelled - got %v instead", entry.name, err)
+		}
+		if grid.String() != hardPuzzles[0] {
+			t.Errorf("%s: expected the grid to be left as it was - got %s instead", entry.name, grid)
+		}
+	}
+}
+
+func TestSolveTimedOut(t *testing.T) {
+	for _, entry := range solvers {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		grid, _ := NewGridFromString(hardPuzzles[0])
+		ch := make(chan UpdateEvent)
+		go func() {
+			// a slow reader
+			for range ch {
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		err := entry.solver.Solve(ctx, &grid, ch)
+		close(ch)
+		cancel()
+		if err != ErrTimedOut {
+			t.Errorf("%s: expected ErrTimedOut - got %v instead", entry.name, err)
+		}
+	}
+}
+
+func TestSolveUnsolvable(t *testing.T) {
+	// the last cell in the first row has no candidates
+	s := "123456780000000009000000000000000000000000000000000000000000000000000000000000000"
+	for _, entry := range solvers {
+		grid, _ := NewGridFromString(s)
+		if err := entry.solver.Solve(context.Background(), &grid, nil); err != ErrUnsolvable {
+			t.Errorf("%s: expected ErrUnsolvable - got %v instead", entry.name, err)
+		}
+	}
+}
+
+func TestSolveInvalid(t *testing.T) {
+	s := "110000000000000000000000000000000000000000000000000000000000000000000000000000000"
+	for _, entry := range solvers {
+		grid, _ := NewGridFromString(s)
+		err := entry.solver.Solve(context.Background(), &grid, nil)
+		if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("%s: expected a *ValidationError - got %v instead", entry.name, err)
+		}
+	}
+}
+
+func TestNewSolver(t *testing.T) {
+	if _, err := NewSolver("dlx", Options{}); err != nil {
+		t.Errorf("unexpected error for dlx: %v", err)
+	}
+	if _, err := NewSolver("backtrack", Options{}); err != nil {
+		t.Errorf("unexpected error for backtrack: %v", err)
+	}
+	if _, err := NewSolver("bogus", Options{}); err == nil {
+		t.Error("expected an error for an unknown algorithm")
+	}
+}
+
+func benchmarkSolver(b *testing.B, solver Solver) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for _, s := range mixedPuzzles {
+			grid, _ := NewGridFromString(s)
+			solver.Solve(context.Background(), &grid, nil)
+		}
+	}
+}
+
+func BenchmarkBacktracker(b *testing.B) {
+	benchmarkSolver(b, Backtracker{Options: Options{Order: MinimumRemaining, Propagate: true}})
+}
+
+func BenchmarkDancingLinks(b *testing.B) {
+	benchmarkSolver(b, DancingLinks{})
+}
@@ -0,0 +1,185 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// PuzzleSource supplies new puzzles. Implementations are safe for concurrent
+// use.
+type PuzzleSource interface {
+	Puzzle(ctx context.Context) (Grid, error)
+}
+
+// GeneratorSource generates puzzles locally.
+type GeneratorSource struct {
+	options GenerateOptions
+	lock    sync.Mutex
+	g       *Generator
+}
+
+// NewGeneratorSource returns a source which generates puzzles meeting the
+// options, seeded with seed.
+func NewGeneratorSource(seed int64, options GenerateOptions) *GeneratorSource {
+	return &GeneratorSource{options: options, g: NewGenerator(seed)}
+}
+
+// Puzzle generates a puzzle meeting the source's options.
+func (s *GeneratorSource) Puzzle(ctx context.Context) (Grid, error) {
+	return s.PuzzleWithOptions(ctx, s.options)
+}
+
+// PuzzleWithOptions generates a puzzle meeting options instead of the
+// source's own options.
+func (s *GeneratorSource) PuzzleWithOptions(ctx context.Context, options GenerateOptions) (Grid, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.g.GenerateContext(ctx, options)
+}
+
+// FileSource draws puzzles at random from a file, or from every file in a
+// directory, in any format accepted by ParseGrids.
+type FileSource struct {
+	grids []Grid
+	lock  sync.Mutex
+	rand  *rand.Rand
+}
+
+// NewFileSource reads every puzzle in path, which may be a file or a
+// directory. Files in subdirectories and files whose names start with . are
+// skipped.
+func NewFileSource(path string, seed int64) (*FileSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, info := range infos {
+			if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+				continue
+			}
+			files = append(files, filepath.Join(path, info.Name()))
+		}
+	}
+
+	s := &FileSource{rand: rand.New(rand.NewSource(seed))}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		grids, err := ParseGrids(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		s.grids = append(s.grids, grids...)
+	}
+	if len(s.grids) == 0 {
+		return nil, fmt.Errorf("no puzzles found in %s", path)
+	}
+	return s, nil
+}
+
+// Len returns the number of puzzles in the source.
+func (s *FileSource) Len() int {
+	return len(s.grids)
+}
+
+// Puzzle returns a random puzzle from the source.
+func (s *FileSource) Puzzle(ctx context.Context) (Grid, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.grids[s.rand.Intn(len(s.grids))], nil
+}
+
+// HTTPSource fetches puzzles from a URL serving a JSON object with the puzzle
+// in one of its fields, such as the /puzzle endpoint of another solver.
+type HTTPSource struct {
+	URL string
+	// Field holds the puzzle, in any format accepted by ParseGrid. It
+	// defaults to "puzzle".
+	Field string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Puzzle fetches a puzzle from the URL.
+func (s HTTPSource) Puzzle(ctx context.Context) (Grid, error) {
+	field := s.Field
+	if field == "" {
+		field = "puzzle"
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequest("GET", s.URL, nil)
+	if err != nil {
+		return Grid{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return Grid{}, fmt.Errorf("could not load puzzle from %s: %v", s.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Grid{}, fmt.Errorf("could not load puzzle from %s: %s", s.URL, resp.Status)
+	}
+
+	var reply map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return Grid{}, fmt.Errorf("could not decode reply from %s: %v", s.URL, err)
+	}
+	puzzle, ok := reply[field].(string)
+	if !ok {
+		if message, ok := reply["error"].(string); ok {
+			return Grid{}, fmt.Errorf("%s returned an error: %s", s.URL, message)
+		}
+		return Grid{}, fmt.Errorf("reply from %s has no %s string", s.URL, field)
+	}
+	grid, err := ParseGrid(puzzle)
+	if err != nil {
+		return grid, fmt.Errorf("could not parse puzzle from %s: %v", s.URL, err)
+	}
+	return grid, nil
+}
+
+// FallbackSource tries each of its sources in turn, returning the first
+// puzzle it gets.
+type FallbackSource []PuzzleSource
+
+// Puzzle returns a puzzle from the first source which supplies one. If
+// every source fails, the error lists each source's error.
+func (s FallbackSource) Puzzle(ctx context.Context) (Grid, error) {
+	if len(s) == 0 {
+		return Grid{}, fmt.Errorf("no puzzle sources")
+	}
+	var messages []string
+	for _, source := range s {
+		grid, err := source.Puzzle(ctx)
+		if err == nil {
+			return grid, nil
+		}
+		if ctx.Err() != nil {
+			return Grid{}, ctx.Err()
+		}
+		messages = append(messages, err.Error())
+	}
+	return Grid{}, fmt.Errorf("every puzzle source failed: %s", strings.Join(messages, "; "))
+}
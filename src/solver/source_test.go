@@ -0,0 +1,160 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type failingSource struct{}
+
+func (failingSource) Puzzle(ctx context.Context) (Grid, error) {
+	return Grid{}, errors.New("no puzzles here")
+}
+
+func TestGeneratorSource(t *testing.T) {
+	s := NewGeneratorSource(1, GenerateOptions{Symmetry: Rotational180})
+	grid, err := s.Puzzle(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !grid.HasUniqueSolution() {
+		t.Errorf("%s does not have a unique solution", grid)
+	}
+}
+
+func TestGeneratorSourceCancelled(t *testing.T) {
+	s := NewGeneratorSource(1, GenerateOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Puzzle(ctx); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "puzzles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"hard.sdm":   strings.Join(hardPuzzles, "\n"),
+		"easy.txt":   strings.Join(easyPuzzles, "\n"),
+		".ignored":   "not a puzzle",
+		"single.sdk": "[Puzzle]\n" + testPuzzle,
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewFileSource(dir, 1)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if expected := len(hardPuzzles) + len(easyPuzzles) + 1; s.Len() != expected {
+		t.Errorf("expected %d puzzles - got %d instead", expected, s.Len())
+	}
+	if _, err := s.Puzzle(context.Background()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	s, err = NewFileSource(filepath.Join(dir, "single.sdk"), 1)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	grid, _ := s.Puzzle(context.Background())
+	if grid.String() != testPuzzle {
+		t.Errorf("expected %s - got %s instead", testPuzzle, grid)
+	}
+
+	if _, err := NewFileSource(filepath.Join(dir, ".ignored"), 1); err == nil {
+		t.Error("expected an error for a file without puzzles")
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/puzzle":
+			fmt.Fprintf(w, `{"puzzle":"%s","rating":{"value":1.2}}`, testPuzzle)
+		case "/other":
+			fmt.Fprintf(w, `{"grid":"%s"}`, testPuzzle)
+		case "/error":
+			fmt.Fprint(w, `{"error":"out of puzzles"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tables := []struct {
+		source HTTPSource
+		ok     bool
+	}{
+		{HTTPSource{URL: server.URL + "/puzzle"}, true},
+		{HTTPSource{URL: server.URL + "/other", Field: "grid"}, true},
+		{HTTPSource{URL: server.URL + "/other"}, false},
+		{HTTPSource{URL: server.URL + "/error"}, false},
+		{HTTPSource{URL: server.URL + "/missing"}, false},
+	}
+	for _, table := range tables {
+		grid, err := table.source.Puzzle(context.Background())
+		if table.ok && (err != nil || grid.String() != testPuzzle) {
+			t.Errorf("%s: expected %s - got %s, %v instead", table.source.URL, testPuzzle, grid, err)
+		}
+		if !table.ok && err == nil {
+			t.Errorf("%s: expected an error", table.source.URL)
+		}
+	}
+}
+
+func TestDavidBauSource(t *testing.T) {
+	grid, _ := NewGridFromString(testPuzzle)
+	var page strings.Builder
+	for row := 0; row < 9; row++ {
+		page.WriteString("+---+---+---+---+---+---+---+---+---+\n")
+		for column := 0; column < 9; column++ {
+			value := " "
+			if v := grid[row*9+column]; v != 0 {
+				value = fmt.Sprint(v)
+			}
+			fmt.Fprintf(&page, "| %s ", value)
+		}
+		page.WriteString("|\n")
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, page.String())
+	}))
+	defer server.Close()
+
+	loaded, err := DavidBauSource{URL: server.URL}.Puzzle(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if loaded != grid {
+		t.Errorf("expected %s - got %s instead", grid, loaded)
+	}
+}
+
+func TestFallbackSource(t *testing.T) {
+	s := FallbackSource{failingSource{}, NewGeneratorSource(1, GenerateOptions{})}
+	if _, err := s.Puzzle(context.Background()); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	s = FallbackSource{failingSource{}, failingSource{}}
+	_, err := s.Puzzle(context.Background())
+	if err == nil || strings.Count(err.Error(), "no puzzles here") != 2 {
+		t.Errorf("expected both errors to be reported - got %v", err)
+	}
+}
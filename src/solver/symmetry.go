@@ -0,0 +1,82 @@
+package solver
+
+import "fmt"
+
+// Symmetry is a pattern the givens of a generated puzzle follow.
+type Symmetry int
+
+// Symmetries for generated puzzles.
+const (
+	// NoSymmetry places givens anywhere.
+	NoSymmetry Symmetry = iota
+	// Rotational180 keeps the givens the same when the grid is turned
+	// half way round.
+	Rotational180
+	// Mirror keeps the givens the same when the grid is flipped left to
+	// right.
+	Mirror
+	// Diagonal keeps the givens the same when the grid is flipped about
+	// its main diagonal.
+	Diagonal
+	// Rotational90 keeps the givens the same when the grid is turned a
+	// quarter of the way round.
+	Rotational90
+)
+
+var symmetryNames = []string{"none", "rot180", "mirror", "diagonal", "rot90"}
+
+func (s Symmetry) String() string {
+	if s < NoSymmetry || s > Rotational90 {
+		return fmt.Sprintf("Symmetry(%d)", int(s))
+	}
+	return symmetryNames[s]
+}
+
+// ParseSymmetry returns the Symmetry with the given name. An empty name
+// returns NoSymmetry.
+func ParseSymmetry(name string) (Symmetry, error) {
+	if name == "" {
+		return NoSymmetry, nil
+	}
+	for s, symmetryName := range symmetryNames {
+		if name == symmetryName {
+			return Symmetry(s), nil
+		}
+	}
+	return NoSymmetry, fmt.Errorf("unknown symmetry %s", name)
+}
+
+// image returns the cell a cell maps to under the symmetry.
+func (s Symmetry) image(index int) int {
+	row, column := rowOf[index], columnOf[index]
+	switch s {
+	case Rotational180:
+		return (8-row)*9 + 8 - column
+	case Mirror:
+		return row*9 + 8 - column
+	case Diagonal:
+		return column*9 + row
+	case Rotational90:
+		return column*9 + 8 - row
+	}
+	return index
+}
+
+// orbits splits the cells into groups which map onto each other under the
+// symmetry, so that a given in one cell of a group needs givens in the rest.
+func (s Symmetry) orbits() [][]int {
+	var orbits [][]int
+	var seen [81]bool
+	for i := 0; i < 81; i++ {
+		if seen[i] {
+			continue
+		}
+		var orbit []int
+		for j := i; !seen[j]; j = s.image(j) {
+			seen[j] = true
+			orbit = append(orbit, j)
+		}
+		orbits = append(orbits, orbit)
+	}
+	return orbits
+}
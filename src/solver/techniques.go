@@ -0,0 +1,540 @@
+package solver
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Names of the techniques used by the logical solver.
+const (
+	NakedSingle     = "Naked single"
+	HiddenSingle    = "Hidden single"
+	Pointing        = "Locked candidates (pointing)"
+	Claiming        = "Locked candidates (claiming)"
+	NakedPair       = "Naked pair"
+	NakedTriple     = "Naked triple"
+	HiddenPair      = "Hidden pair"
+	HiddenTriple    = "Hidden triple"
+	XWing           = "X-Wing"
+	Swordfish       = "Swordfish"
+	XYWing          = "XY-Wing"
+	SimpleColouring = "Simple colouring"
+)
+
+// technique finds a deduction of one kind in a grid.
+type technique struct {
+	name string
+	find func(p *pencilmarks) (Step, bool)
+}
+
+var (
+	nakedSingle     = technique{NakedSingle, findNakedSingle}
+	hiddenSingle    = technique{HiddenSingle, findHiddenSingle}
+	pointing        = technique{Pointing, findPointing}
+	claiming        = technique{Claiming, findClaiming}
+	nakedPair       = technique{NakedPair, findNakedSubset(2)}
+	nakedTriple     = technique{NakedTriple, findNakedSubset(3)}
+	hiddenPair      = technique{HiddenPair, findHiddenSubset(2)}
+	hiddenTriple    = technique{HiddenTriple, findHiddenSubset(3)}
+	xWing           = technique{XWing, findFish(2)}
+	swordfish       = technique{Swordfish, findFish(3)}
+	xyWing          = technique{XYWing, findXYWing}
+	simpleColouring = technique{SimpleColouring, findSimpleColouring}
+)
+
+// explainTechniques are the techniques used by Explain, in the order a
+// person would usually look for them.
+var explainTechniques = []technique{
+	nakedSingle,
+	hiddenSingle,
+	pointing,
+	claiming,
+	nakedPair,
+	hiddenPair,
+	nakedTriple,
+	hiddenTriple,
+	xWing,
+	swordfish,
+	xyWing,
+	simpleColouring,
+}
+
+var subsetNames = map[int]string{2: "pair", 3: "triple"}
+var fishNames = map[int]string{2: XWing, 3: Swordfish}
+
+func findNakedSingle(p *pencilmarks) (Step, bool) {
+	for i, value := range p.grid {
+		if value != 0 || p.candidates[i].count() != 1 {
+			continue
+		}
+		digit := p.candidates[i].lowest()
+		return Step{
+			Technique:   NakedSingle,
+			Cells:       []int{i},
+			Placements:  []Candidate{{i, digit}},
+			Description: fmt.Sprintf("%s has only one candidate left, so it must be %d.", cellName(i), digit),
+		}, true
+	}
+	return Step{}, false
+}
+
+// boxes first, as hidden singles are easiest to spot in a box
+var hiddenSingleUnits = []int{
+	18, 19, 20, 21, 22, 23, 24, 25, 26,
+	0, 1, 2, 3, 4, 5, 6, 7, 8,
+	9, 10, 11, 12, 13, 14, 15, 16, 17,
+}
+
+func findHiddenSingle(p *pencilmarks) (Step, bool) {
+	for _, u := range hiddenSingleUnits {
+		for digit := 1; digit <= 9; digit++ {
+			cells := p.cellsWith(u, digit)
+			if len(cells) != 1 {
+				continue
+			}
+			i := cells[0]
+			return Step{
+				Technique:   HiddenSingle,
+				Cells:       []int{i},
+				Placements:  []Candidate{{i, digit}},
+				Description: fmt.Sprintf("%d can only go in one cell of %s, so %s must be %d.", digit, unitName(u), cellName(i), digit),
+				unit:        kindOf(u),
+			}, true
+		}
+	}
+	return Step{}, false
+}
+
+// eliminate returns the candidates for digit in cells, skipping the cells in
+// except.
+func (p *pencilmarks) eliminate(cells []int, digit int, except []int) []Candidate {
+	var eliminations []Candidate
+	m := maskOf(digit)
+	for _, i := range cells {
+		if p.candidates[i]&m != 0 && !containsInt(except, i) {
+			eliminations = append(eliminations, Candidate{i, digit})
+		}
+	}
+	return eliminations
+}
+
+func findPointing(p *pencilmarks) (Step, bool) {
+	for b := 0; b < 9; b++ {
+		box := 18 + b
+		for digit := 1; digit <= 9; digit++ {
+			cells := p.cellsWith(box, digit)
+			if len(cells) < 2 {
+				continue
+			}
+			for _, line := range []int{rowOf[cells[0]], 9 + columnOf[cells[0]]} {
+				if !allInUnit(cells, line) {
+					continue
+				}
+				eliminations := p.eliminate(units[line][:], digit, cells)
+				if len(eliminations) == 0 {
+					continue
+				}
+				return Step{
+					Technique:    Pointing,
+					Cells:        cells,
+					Eliminations: eliminations,
+					Description: fmt.Sprintf("In %s, %d can only go in %s, so it can be removed from the rest of %s: %s.",
+						unitName(box), digit, unitName(line), unitName(line), cellNames(cellsOf(eliminations))),
+				}, true
+			}
+		}
+	}
+	return Step{}, false
+}
+
+func findClaiming(p *pencilmarks) (Step, bool) {
+	for line := 0; line < 18; line++ {
+		for digit := 1; digit <= 9; digit++ {
+			cells := p.cellsWith(line, digit)
+			if len(cells) < 2 {
+				continue
+			}
+			box := 18 + boxOf[cells[0]]
+			if !allInUnit(cells, box) {
+				continue
+			}
+			eliminations := p.eliminate(units[box][:], digit, cells)
+			if len(eliminations) == 0 {
+				continue
+			}
+			return Step{
+				Technique:    Claiming,
+				Cells:        cells,
+				Eliminations: eliminations,
+				Description: fmt.Sprintf("In %s, %d can only go in %s, so it can be removed from the rest of %s: %s.",
+					unitName(line), digit, unitName(box), unitName(box), cellNames(cellsOf(eliminations))),
+			}, true
+		}
+	}
+	return Step{}, false
+}
+
+// findNakedSubset returns a technique finder for n cells in a unit which
+// only hold n candidates between them.
+func findNakedSubset(n int) func(p *pencilmarks) (Step, bool) {
+	return func(p *pencilmarks) (Step, bool) {
+		for u := range units {
+			var empty []int
+			for _, i := range units[u] {
+				if count := p.candidates[i].count(); count >= 2 && count <= n {
+					empty = append(empty, i)
+				}
+			}
+			var step Step
+			found := false
+			combinations(len(empty), n, func(chosen []int) bool {
+				cells := make([]int, n)
+				var union digitMask
+				for k, c := range chosen {
+					cells[k] = empty[c]
+					union |= p.candidates[empty[c]]
+				}
+				if union.count() != n {
+					return true
+				}
+				var eliminations []Candidate
+				for _, digit := range union.digits() {
+					eliminations = append(eliminations, p.eliminate(units[u][:], digit, cells)...)
+				}
+				if len(eliminations) == 0 {
+					return true
+				}
+				step = Step{
+					Technique:    "Naked " + subsetNames[n],
+					Cells:        cells,
+					Eliminations: eliminations,
+					Description: fmt.Sprintf("%s can only hold %s between them, so those digits can be removed from the rest of %s: %s.",
+						cellNames(cells), digitNames(union), unitName(u), eliminationNames(eliminations)),
+				}
+				found = true
+				return false
+			})
+			if found {
+				return step, true
+			}
+		}
+		return Step{}, false
+	}
+}
+
+// findHiddenSubset returns a technique finder for n digits which can only go
+// in the same n cells of a unit.
+func findHiddenSubset(n int) func(p *pencilmarks) (Step, bool) {
+	return func(p *pencilmarks) (Step, bool) {
+		for u := range units {
+			var digits []int
+			positions := map[int][]int{}
+			for digit := 1; digit <= 9; digit++ {
+				cells := p.cellsWith(u, digit)
+				if len(cells) >= 1 && len(cells) <= n {
+					digits = append(digits, digit)
+					positions[digit] = cells
+				}
+			}
+			var step Step
+			found := false
+			combinations(len(digits), n, func(chosen []int) bool {
+				var cells []int
+				var subset digitMask
+				for _, c := range chosen {
+					subset |= maskOf(digits[c])
+					for _, i := range positions[digits[c]] {
+						if !containsInt(cells, i) {
+							cells = append(cells, i)
+						}
+					}
+				}
+				if len(cells) != n {
+					return true
+				}
+				sort.Ints(cells)
+				var eliminations []Candidate
+				for _, i := range cells {
+					for _, digit := range (p.candidates[i] &^ subset).digits() {
+						eliminations = append(eliminations, Candidate{i, digit})
+					}
+				}
+				if len(eliminations) == 0 {
+					return true
+				}
+				step = Step{
+					Technique:    "Hidden " + subsetNames[n],
+					Cells:        cells,
+					Eliminations: eliminations,
+					Description: fmt.Sprintf("In %s, %s can only go in %s, so the other candidates can be removed from those cells: %s.",
+						unitName(u), digitNames(subset), cellNames(cells), eliminationNames(eliminations)),
+				}
+				found = true
+				return false
+			})
+			if found {
+				return step, true
+			}
+		}
+		return Step{}, false
+	}
+}
+
+// findFish returns a technique finder for a digit which can only go in the
+// same n columns of n rows, or the same n rows of n columns - an X-Wing for
+// n=2 and a Swordfish for n=3.
+func findFish(n int) func(p *pencilmarks) (Step, bool) {
+	return func(p *pencilmarks) (Step, bool) {
+		for digit := 1; digit <= 9; digit++ {
+			// base lines are rows then columns, with the cover lines
+			// running the other way
+			for _, base := range []int{0, 9} {
+				cover := 9 - base
+				var lines []int
+				for line := base; line < base+9; line++ {
+					if count := len(p.cellsWith(line, digit)); count >= 2 && count <= n {
+						lines = append(lines, line)
+					}
+				}
+				var step Step
+				found := false
+				combinations(len(lines), n, func(chosen []int) bool {
+					var baseLines, coverLines, cells []int
+					for _, c := range chosen {
+						baseLines = append(baseLines, lines[c])
+						for _, i := range p.cellsWith(lines[c], digit) {
+							cells = append(cells, i)
+							coverLine := cover + rowOf[i]
+							if cover == 9 {
+								coverLine = cover + columnOf[i]
+							}
+							if !containsInt(coverLines, coverLine) {
+								coverLines = append(coverLines, coverLine)
+							}
+						}
+					}
+					if len(coverLines) != n {
+						return true
+					}
+					sort.Ints(coverLines)
+					var eliminations []Candidate
+					for _, line := range coverLines {
+						eliminations = append(eliminations, p.eliminate(units[line][:], digit, cells)...)
+					}
+					if len(eliminations) == 0 {
+						return true
+					}
+					sort.Ints(cells)
+					step = Step{
+						Technique:    fishNames[n],
+						Cells:        cells,
+						Eliminations: eliminations,
+						Description: fmt.Sprintf("In %s, %d can only go in %s, so it can be removed from the rest of those %ss: %s.",
+							lineNames(baseLines), digit, lineNames(coverLines), unitNames[cover/9], cellNames(cellsOf(eliminations))),
+					}
+					found = true
+					return false
+				})
+				if found {
+					return step, true
+				}
+			}
+		}
+		return Step{}, false
+	}
+}
+
+func findXYWing(p *pencilmarks) (Step, bool) {
+	for pivot := 0; pivot < 81; pivot++ {
+		if p.candidates[pivot].count() != 2 {
+			continue
+		}
+		for _, a := range peers[pivot] {
+			ca := p.candidates[a]
+			shared := ca & p.candidates[pivot]
+			if ca.count() != 2 || shared.count() != 1 {
+				continue
+			}
+			// a holds x and z, where x is shared with the pivot
+			z := (ca &^ shared).lowest()
+			y := (p.candidates[pivot] &^ shared).lowest()
+			for _, b := range peers[pivot] {
+				if b == a || p.candidates[b] != maskOf(y)|maskOf(z) {
+					continue
+				}
+				var eliminations []Candidate
+				for _, i := range peers[a] {
+					if i != b && i != pivot && sees(i, b) && p.candidates[i].has(z) {
+						eliminations = append(eliminations, Candidate{i, z})
+					}
+				}
+				if len(eliminations) == 0 {
+					continue
+				}
+				sort.Slice(eliminations, func(i, j int) bool { return eliminations[i].Index < eliminations[j].Index })
+				return Step{
+					Technique:    XYWing,
+					Cells:        []int{pivot, a, b},
+					Eliminations: eliminations,
+					Description: fmt.Sprintf("%s is either %d or %d. Either way, one of %s and %s must be %d, so %d can be removed from every cell which sees both: %s.",
+						cellName(pivot), shared.lowest(), y, cellName(a), cellName(b), z, z, cellNames(cellsOf(eliminations))),
+				}, true
+			}
+		}
+	}
+	return Step{}, false
+}
+
+func findSimpleColouring(p *pencilmarks) (Step, bool) {
+	for digit := 1; digit <= 9; digit++ {
+		// link the cells of every unit where the digit has two places
+		links := map[int][]int{}
+		for u := range units {
+			cells := p.cellsWith(u, digit)
+			if len(cells) == 2 {
+				links[cells[0]] = append(links[cells[0]], cells[1])
+				links[cells[1]] = append(links[cells[1]], cells[0])
+			}
+		}
+
+		colour := map[int]int{}
+		for start := 0; start < 81; start++ {
+			if _, done := colour[start]; done || len(links[start]) == 0 {
+				continue
+			}
+			// colour the chain alternately, starting from start
+			chain := []int{start}
+			colour[start] = 0
+			for k := 0; k < len(chain); k++ {
+				for _, next := range links[chain[k]] {
+					if _, done := colour[next]; !done {
+						colour[next] = 1 - colour[chain[k]]
+						chain = append(chain, next)
+					}
+				}
+			}
+			if len(chain) < 3 {
+				// a single link tells us nothing
+				continue
+			}
+			sort.Ints(chain)
+
+			// colour wrap - two cells of the same colour see each other, so
+			// that colour is false
+			for _, a := range chain {
+				for _, b := range chain {
+					if a >= b || colour[a] != colour[b] || !sees(a, b) {
+						continue
+					}
+					var eliminations []Candidate
+					for _, i := range chain {
+						if colour[i] == colour[a] {
+							eliminations = append(eliminations, Candidate{i, digit})
+						}
+					}
+					return Step{
+						Technique:    SimpleColouring,
+						Cells:        chain,
+						Eliminations: eliminations,
+						Description: fmt.Sprintf("Colouring the chain of cells where %d has only two places in a unit, %s and %s get the same colour but see each other, so %d can be removed from every cell of that colour: %s.",
+							digit, cellName(a), cellName(b), digit, cellNames(cellsOf(eliminations))),
+					}, true
+				}
+			}
+
+			// colour trap - a cell which sees both colours can't hold the
+			// digit
+			var eliminations []Candidate
+			for i := 0; i < 81; i++ {
+				if _, inChain := colour[i]; inChain || !p.candidates[i].has(digit) {
+					continue
+				}
+				var seen [2]bool
+				for _, c := range chain {
+					if sees(i, c) {
+						seen[colour[c]] = true
+					}
+				}
+				if seen[0] && seen[1] {
+					eliminations = append(eliminations, Candidate{i, digit})
+				}
+			}
+			if len(eliminations) > 0 {
+				return Step{
+					Technique:    SimpleColouring,
+					Cells:        chain,
+					Eliminations: eliminations,
+					Description: fmt.Sprintf("Colouring the chain of cells where %d has only two places in a unit, one of the two colours must be %d, so it can be removed from cells which see both colours: %s.",
+						digit, digit, cellNames(cellsOf(eliminations))),
+				}, true
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// combinations calls fn with every way of choosing k of the numbers from 0 to
+// n-1, in ascending order. It stops early if fn returns false.
+func combinations(n, k int, fn func(chosen []int) bool) {
+	if k > n {
+		return
+	}
+	chosen := make([]int, k)
+	for i := range chosen {
+		chosen[i] = i
+	}
+	for {
+		if !fn(chosen) {
+			return
+		}
+		// find the rightmost index which can still move up
+		i := k - 1
+		for i >= 0 && chosen[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		chosen[i]++
+		for j := i + 1; j < k; j++ {
+			chosen[j] = chosen[j-1] + 1
+		}
+	}
+}
+
+func allInUnit(cells []int, u int) bool {
+	for _, i := range cells {
+		if !containsInt(units[u][:], i) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func cellsOf(candidates []Candidate) []int {
+	var cells []int
+	for _, c := range candidates {
+		if !containsInt(cells, c.Index) {
+			cells = append(cells, c.Index)
+		}
+	}
+	return cells
+}
+
+// lineNames describes several units of the same kind - "rows 2 and 6".
+func lineNames(lines []int) string {
+	var numbers []string
+	for _, u := range lines {
+		numbers = append(numbers, fmt.Sprint(u%9+1))
+	}
+	return unitNames[lines[0]/9] + "s " + joinWords(numbers)
+}
@@ -0,0 +1,255 @@
+package solver
+
+import "math/rand"
+
+// Transform is a symmetry of Sudoku - a change to a grid which keeps valid
+// grids valid and unique puzzles unique. Applying it optionally transposes
+// the grid, then rearranges its rows and columns and relabels its digits.
+//
+// Rows and Columns must only swap bands and stacks around and swap lines
+// within a band or stack, and Digits must be a permutation of 1 to 9, or the
+// result is no longer a Sudoku.
+type Transform struct {
+	// Transpose flips the grid about its main diagonal first.
+	Transpose bool
+	// Row r of the result is row Rows[r] of the transposed grid.
+	Rows [9]int
+	// Column c of the result is column Columns[c] of the transposed grid.
+	Columns [9]int
+	// Digit d becomes Digits[d]. Digits[0] is always 0.
+	Digits [10]int
+}
+
+// IdentityTransform returns the Transform which leaves grids unchanged.
+func IdentityTransform() Transform {
+	var t Transform
+	for i := 0; i < 9; i++ {
+		t.Rows[i] = i
+		t.Columns[i] = i
+		t.Digits[i+1] = i + 1
+	}
+	return t
+}
+
+// Apply returns the transformed grid.
+func (t Transform) Apply(g Grid) Grid {
+	var result Grid
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			row, column := t.Rows[r], t.Columns[c]
+			if t.Transpose {
+				row, column = column, row
+			}
+			result[r*9+c] = t.Digits[g[row*9+column]]
+		}
+	}
+	return result
+}
+
+// Inverse returns the Transform which undoes t.
+func (t Transform) Inverse() Transform {
+	var inverse Transform
+	inverse.Transpose = t.Transpose
+	var rows, columns [9]int
+	for i := 0; i < 9; i++ {
+		rows[t.Rows[i]] = i
+		columns[t.Columns[i]] = i
+		inverse.Digits[t.Digits[i+1]] = i + 1
+	}
+	if t.Transpose {
+		rows, columns = columns, rows
+	}
+	inverse.Rows, inverse.Columns = rows, columns
+	return inverse
+}
+
+// Then returns the Transform which applies t and then u.
+func (t Transform) Then(u Transform) Transform {
+	var result Transform
+	result.Transpose = t.Transpose != u.Transpose
+	for i := 0; i < 9; i++ {
+		if u.Transpose {
+			result.Rows[i] = t.Columns[u.Rows[i]]
+			result.Columns[i] = t.Rows[u.Columns[i]]
+		} else {
+			result.Rows[i] = t.Rows[u.Rows[i]]
+			result.Columns[i] = t.Columns[u.Columns[i]]
+		}
+		result.Digits[i+1] = u.Digits[t.Digits[i+1]]
+	}
+	return result
+}
+
+// linePermutations are the 1296 ways of rearranging rows or columns which
+// keep bands or stacks together.
+var linePermutations [][9]int
+
+func init() {
+	var groups [][3]int
+	for a := 0; a < 3; a++ {
+		for b := 0; b < 3; b++ {
+			for c := 0; c < 3; c++ {
+				if a != b && a != c && b != c {
+					groups = append(groups, [3]int{a, b, c})
+				}
+			}
+		}
+	}
+	for _, outer := range groups {
+		for _, first := range groups {
+			for _, second := range groups {
+				for _, third := range groups {
+					var p [9]int
+					for i, inner := range [][3]int{first, second, third} {
+						for j := 0; j < 3; j++ {
+							p[i*3+j] = outer[i]*3 + inner[j]
+						}
+					}
+					linePermutations = append(linePermutations, p)
+				}
+			}
+		}
+	}
+}
+
+// reversed is the line order which reverses a grid's rows or columns.
+var reversed = [9]int{8, 7, 6, 5, 4, 3, 2, 1, 0}
+
+// Transposed returns the Transform which flips grids about their main
+// diagonal, from the top left to the bottom right.
+func Transposed() Transform {
+	t := IdentityTransform()
+	t.Transpose = true
+	return t
+}
+
+// Rotate90 returns the Transform which turns grids a quarter of the way
+// round clockwise.
+func Rotate90() Transform {
+	t := Transposed()
+	t.Columns = reversed
+	return t
+}
+
+// Rotate180 returns the Transform which turns grids half way round.
+func Rotate180() Transform {
+	t := IdentityTransform()
+	t.Rows = reversed
+	t.Columns = reversed
+	return t
+}
+
+// Rotate270 returns the Transform which turns grids a quarter of the way
+// round anticlockwise.
+func Rotate270() Transform {
+	t := Transposed()
+	t.Rows = reversed
+	return t
+}
+
+// MirrorHorizontal returns the Transform which flips grids top to bottom,
+// about their horizontal centre line.
+func MirrorHorizontal() Transform {
+	t := IdentityTransform()
+	t.Rows = reversed
+	return t
+}
+
+// MirrorVertical returns the Transform which flips grids left to right,
+// about their vertical centre line.
+func MirrorVertical() Transform {
+	t := IdentityTransform()
+	t.Columns = reversed
+	return t
+}
+
+// MirrorDiagonal returns the Transform which flips grids about their main
+// diagonal. It is the same as Transposed.
+func MirrorDiagonal() Transform {
+	return Transposed()
+}
+
+// MirrorAntiDiagonal returns the Transform which flips grids about the
+// diagonal from the top right to the bottom left.
+func MirrorAntiDiagonal() Transform {
+	t := Transposed()
+	t.Rows = reversed
+	t.Columns = reversed
+	return t
+}
+
+// withinGroup returns the line order which rearranges the three lines of
+// one band or stack, so that line i of the group comes from line order[i].
+func withinGroup(group int, order [3]int) [9]int {
+	lines := IdentityTransform().Rows
+	for i, j := range order {
+		lines[group*3+i] = group*3 + j
+	}
+	return lines
+}
+
+// ofGroups returns the line order which rearranges whole bands or stacks, so
+// that group i comes from group order[i].
+func ofGroups(order [3]int) [9]int {
+	var lines [9]int
+	for i, j := range order {
+		for k := 0; k < 3; k++ {
+			lines[i*3+k] = j*3 + k
+		}
+	}
+	return lines
+}
+
+// PermuteRows returns the Transform which rearranges the rows of a band, so
+// that row i of the band comes from row order[i]. order must be a
+// permutation of 0, 1 and 2, as must the orders given to the other Permute
+// functions.
+func PermuteRows(band int, order [3]int) Transform {
+	t := IdentityTransform()
+	t.Rows = withinGroup(band, order)
+	return t
+}
+
+// PermuteBands returns the Transform which rearranges the bands, so that
+// band i comes from band order[i].
+func PermuteBands(order [3]int) Transform {
+	t := IdentityTransform()
+	t.Rows = ofGroups(order)
+	return t
+}
+
+// PermuteColumns returns the Transform which rearranges the columns of a
+// stack, so that column i of the stack comes from column order[i].
+func PermuteColumns(stack int, order [3]int) Transform {
+	t := IdentityTransform()
+	t.Columns = withinGroup(stack, order)
+	return t
+}
+
+// PermuteStacks returns the Transform which rearranges the stacks, so that
+// stack i comes from stack order[i].
+func PermuteStacks(order [3]int) Transform {
+	t := IdentityTransform()
+	t.Columns = ofGroups(order)
+	return t
+}
+
+// RelabelDigits returns the Transform which turns digit d into digits[d-1].
+// digits must be a permutation of 1 to 9.
+func RelabelDigits(digits [9]int) Transform {
+	t := IdentityTransform()
+	copy(t.Digits[1:], digits[:])
+	return t
+}
+
+// RandomTransform returns a Transform picked at random from every possible
+// Transform.
+func RandomTransform(r *rand.Rand) Transform {
+	t := Transform{Transpose: r.Intn(2) == 1}
+	t.Rows = linePermutations[r.Intn(len(linePermutations))]
+	t.Columns = linePermutations[r.Intn(len(linePermutations))]
+	for i, d := range r.Perm(9) {
+		t.Digits[i+1] = d + 1
+	}
+	return t
+}
@@ -0,0 +1,121 @@
+package solver
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	grid, _ := NewGridFromString(testPuzzle)
+	if IdentityTransform().Apply(grid) != grid {
+		t.Error("expected the identity to leave the grid unchanged")
+	}
+	for n := 0; n < 100; n++ {
+		a, b := RandomTransform(r), RandomTransform(r)
+		transformed := a.Apply(grid)
+		if transformed.Validate() != nil || !transformed.HasUniqueSolution() {
+			t.Fatalf("%+v turned %s into %s, which is not a unique puzzle", a, grid, transformed)
+		}
+		if a.Inverse().Apply(transformed) != grid {
+			t.Errorf("inverse of %+v did not undo it", a)
+		}
+		if a.Then(b).Apply(grid) != b.Apply(transformed) {
+			t.Errorf("%+v then %+v did not compose", a, b)
+		}
+	}
+}
+
+// rows returns the 81 character form of a grid whose first rows are given,
+// with "" for an empty row and the rows after them empty.
+func rows(lines ...string) string {
+	s := ""
+	for _, line := range lines {
+		s += line + strings.Repeat("0", 9-len(line))
+	}
+	return s + strings.Repeat("0", 81-len(s))
+}
+
+func TestTransformConstructors(t *testing.T) {
+	grid, _ := NewGridFromString(rows("123000000", "400000000"))
+	tables := []struct {
+		name      string
+		transform Transform
+		expected  string
+	}{
+		{"transposed", Transposed(), rows("140000000", "200000000", "300000000")},
+		{"rotate 90", Rotate90(), rows("000000041", "000000002", "000000003")},
+		{"rotate 180", Rotate180(), rows("", "", "", "", "", "", "", "000000004", "000000321")},
+		{"rotate 270", Rotate270(), rows("", "", "", "", "", "", "300000000", "200000000", "140000000")},
+		{"mirror horizontal", MirrorHorizontal(), rows("", "", "", "", "", "", "", "400000000", "123000000")},
+		{"mirror vertical", MirrorVertical(), rows("000000321", "000000004")},
+		{"mirror diagonal", MirrorDiagonal(), rows("140000000", "200000000", "300000000")},
+		{"mirror anti-diagonal", MirrorAntiDiagonal(), rows("", "", "", "", "", "", "000000003", "000000002", "000000041")},
+		{"permute rows", PermuteRows(0, [3]int{2, 0, 1}), rows("", "123000000", "400000000")},
+		{"permute bands", PermuteBands([3]int{1, 2, 0}), rows("", "", "", "", "", "", "123000000", "400000000")},
+		{"permute columns", PermuteColumns(0, [3]int{1, 2, 0}), rows("231000000", "004000000")},
+		{"permute stacks", PermuteStacks([3]int{2, 0, 1}), rows("000123000", "000400000")},
+	}
+	for _, table := range tables {
+		if result := table.transform.Apply(grid).String(); result != table.expected {
+			t.Errorf("%s: expected %s - got %s instead", table.name, table.expected, result)
+		}
+	}
+
+	puzzle, _ := NewGridFromString(testPuzzle)
+	if Rotate90().Then(Rotate90()).Apply(puzzle) != Rotate180().Apply(puzzle) {
+		t.Error("expected two quarter turns to make a half turn")
+	}
+	if Rotate90().Inverse().Apply(puzzle) != Rotate270().Apply(puzzle) {
+		t.Error("expected the inverse of a clockwise quarter turn to be an anticlockwise one")
+	}
+	relabelled := RelabelDigits([9]int{2, 3, 4, 5, 6, 7, 8, 9, 1}).Apply(puzzle)
+	for i, value := range puzzle {
+		if value != 0 && relabelled[i] != value%9+1 {
+			t.Fatalf("expected %d at %s - got %d instead", value%9+1, cellName(i), relabelled[i])
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	puzzles := append(append([]string{testPuzzle}, hardPuzzles...), easyPuzzles...)
+	for _, puzzle := range puzzles {
+		grid, _ := NewGridFromString(puzzle)
+		canonical := grid.Canonical()
+		for n := 0; n < 5; n++ {
+			shuffled := RandomTransform(r).Apply(grid)
+			if shuffled.Canonical() != canonical {
+				t.Errorf("%s and %s have different canonical forms", grid, shuffled)
+			}
+			transform, ok := Equivalent(grid, shuffled)
+			if !ok {
+				t.Errorf("expected %s and %s to be equivalent", grid, shuffled)
+			} else if transform.Apply(grid) != shuffled {
+				t.Errorf("expected %+v to turn %s into %s", transform, grid, shuffled)
+			}
+		}
+	}
+
+	// a puzzle is not equivalent to a solved grid or to another puzzle
+	solution := NewGenerator(1).Solution()
+	a, _ := NewGridFromString(easyPuzzles[0])
+	b, _ := NewGridFromString(easyPuzzles[1])
+	if _, ok := Equivalent(a, b); ok {
+		t.Errorf("did not expect %s and %s to be equivalent", a, b)
+	}
+	if _, ok := Equivalent(solution, a); ok {
+		t.Errorf("did not expect %s and %s to be equivalent", solution, a)
+	}
+	if solution.Canonical() != RandomTransform(r).Apply(solution).Canonical() {
+		t.Errorf("solved grid %s has different canonical forms", solution)
+	}
+}
+
+func BenchmarkCanonical(b *testing.B) {
+	grid, _ := NewGridFromString(hardPuzzles[0])
+	for i := 0; i < b.N; i++ {
+		grid.Canonical()
+	}
+}
@@ -0,0 +1,162 @@
+package solver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Conflict is a pair of cells in the same row, column or box which hold the
+// same digit.
+type Conflict struct {
+	// Unit is "row", "column" or "box", or on a Board "region" or "cage".
+	Unit string `json:"unit"`
+	// UnitIndex is the index of the row, column or box, from 0 to 8 for a
+	// Grid.
+	UnitIndex int `json:"unitIndex"`
+	// Cells holds the indexes of the two cells.
+	Cells [2]int `json:"cells"`
+	Digit int    `json:"digit"`
+}
+
+func (c Conflict) String() string {
+	return c.describe(9)
+}
+
+// describe is like String for a conflict on a board with size rows.
+func (c Conflict) describe(size int) string {
+	return fmt.Sprintf("%c appears twice in %s %d, at %s and %s", symbol(c.Digit), c.Unit, c.UnitIndex+1, cellNameOn(c.Cells[0], size), cellNameOn(c.Cells[1], size))
+}
+
+// Violation is a way the values on a Board break a variant's constraint
+// other than by repeating a value.
+type Violation struct {
+	// Rule names the constraint, such as "thermometer".
+	Rule string `json:"rule"`
+	// Cells holds the indexes of the cells involved, if the problem is with
+	// particular cells rather than the shape of the constraint.
+	Cells []int `json:"cells,omitempty"`
+	// Msg describes the problem.
+	Msg string `json:"msg"`
+}
+
+// ValidationError lists everything wrong with a grid.
+type ValidationError struct {
+	// OutOfRange holds the indexes of cells whose value is not from 0 to 9,
+	// or to the number of rows on a Board.
+	OutOfRange []int      `json:"outOfRange"`
+	Conflicts  []Conflict `json:"conflicts"`
+	// BrokenCages holds the indexes of the cages on a Board whose values
+	// can no longer add up to their sum.
+	BrokenCages []int `json:"brokenCages,omitempty"`
+	// Violations lists the other ways a Board breaks its constraints.
+	Violations []Violation `json:"violations,omitempty"`
+
+	// the number of rows on the board, or 0 for a Grid, and its cages
+	size  int
+	cages []Cage
+}
+
+func (e *ValidationError) Error() string {
+	var problems []string
+	for _, i := range e.OutOfRange {
+		problems = append(problems, fmt.Sprintf("%s is out of range", cellNameOn(i, e.size)))
+	}
+	for _, c := range e.Conflicts {
+		problems = append(problems, c.describe(e.size))
+	}
+	for _, c := range e.BrokenCages {
+		cage := e.cages[c]
+		problems = append(problems, fmt.Sprintf("cage %d at %s cannot add up to %d", c+1, cellNameOn(cage.Cells[0], e.size), cage.Sum))
+	}
+	for _, v := range e.Violations {
+		problems = append(problems, v.Msg)
+	}
+	return "invalid grid: " + strings.Join(problems, "; ")
+}
+
+// Cells returns the indexes of every cell involved in a problem, in
+// ascending order and without duplicates.
+func (e *ValidationError) Cells() []int {
+	involved := make(map[int]bool)
+	for _, i := range e.OutOfRange {
+		involved[i] = true
+	}
+	for _, c := range e.Conflicts {
+		involved[c.Cells[0]] = true
+		involved[c.Cells[1]] = true
+	}
+	for _, c := range e.BrokenCages {
+		for _, i := range e.cages[c].Cells {
+			involved[i] = true
+		}
+	}
+	for _, v := range e.Violations {
+		for _, i := range v.Cells {
+			involved[i] = true
+		}
+	}
+	var cells []int
+	for i := range involved {
+		cells = append(cells, i)
+	}
+	sort.Ints(cells)
+	return cells
+}
+
+// empty reports whether no problems have been found.
+func (e *ValidationError) empty() bool {
+	return len(e.OutOfRange) == 0 && len(e.Conflicts) == 0 && len(e.BrokenCages) == 0 && len(e.Violations) == 0
+}
+
+var unitNames = [3]string{"row", "column", "box"}
+
+// Validate checks that every value in the grid is from 0 to 9 and that no
+// digit appears twice in a row, column or box. Returns a *ValidationError
+// listing every problem found, or nil if there are none.
+func (grid Grid) Validate() error {
+	e := &ValidationError{}
+	for i, value := range grid {
+		if value < 0 || value > 9 {
+			e.OutOfRange = append(e.OutOfRange, i)
+		}
+	}
+	// the rows, columns and boxes of a Grid never change, so check them
+	// directly rather than through a Board and its constraints
+	for u, cells := range units {
+		for a := 0; a < 9; a++ {
+			digit := grid[cells[a]]
+			if digit < 1 || digit > 9 {
+				continue
+			}
+			for b := a + 1; b < 9; b++ {
+				if grid[cells[b]] == digit {
+					e.Conflicts = append(e.Conflicts, Conflict{
+						Unit:      unitNames[u/9],
+						UnitIndex: u % 9,
+						Cells:     [2]int{cells[a], cells[b]},
+						Digit:     digit,
+					})
+				}
+			}
+		}
+	}
+	if e.empty() {
+		return nil
+	}
+	return e
+}
+
+// cellName returns the row and column of a cell, counting from 1.
+func cellName(index int) string {
+	return cellNameOn(index, 9)
+}
+
+// cellNameOn is like cellName for a board with size rows, or a Grid if size
+// is 0.
+func cellNameOn(index, size int) string {
+	if size == 0 {
+		size = 9
+	}
+	return fmt.Sprintf("r%dc%d", index/size+1, index%size+1)
+}
@@ -0,0 +1,61 @@
+package solver
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	grid, _ := NewGridFromString(testPuzzle)
+	if err := grid.Validate(); err != nil {
+		t.Errorf("expected %s to be valid - got %v instead", testPuzzle, err)
+	}
+
+	// a second 9 in the first row, which is also in the first box
+	grid[0] = 9
+	// and a second 9 in the second column
+	grid[73] = 9
+	// and a value that isn't a digit
+	grid[80] = 10
+
+	err := grid.Validate()
+	if err == nil {
+		t.Fatal("expected the grid to be invalid")
+	}
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError - got %T instead", err)
+	}
+
+	expected := []Conflict{
+		{"row", 0, [2]int{0, 2}, 9},
+		{"column", 1, [2]int{28, 73}, 9},
+		{"box", 0, [2]int{0, 2}, 9},
+	}
+	if len(verr.Conflicts) != len(expected) {
+		t.Fatalf("expected conflicts %v - got %v instead", expected, verr.Conflicts)
+	}
+	for i := range expected {
+		if verr.Conflicts[i] != expected[i] {
+			t.Errorf("expected conflict %v - got %v instead", expected[i], verr.Conflicts[i])
+		}
+	}
+	if len(verr.OutOfRange) != 1 || verr.OutOfRange[0] != 80 {
+		t.Errorf("expected cell 80 to be out of range - got %v instead", verr.OutOfRange)
+	}
+
+	cells := verr.Cells()
+	expectedCells := []int{0, 2, 28, 73, 80}
+	if len(cells) != len(expectedCells) {
+		t.Fatalf("expected cells %v - got %v instead", expectedCells, cells)
+	}
+	for i := range cells {
+		if cells[i] != expectedCells[i] {
+			t.Fatalf("expected cells %v - got %v instead", expectedCells, cells)
+		}
+	}
+}
+
+func TestValidateNegative(t *testing.T) {
+	grid := Grid{-1}
+	if grid.Validate() == nil {
+		t.Error("expected a negative value to be rejected")
+	}
+}
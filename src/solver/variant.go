@@ -0,0 +1,142 @@
+package solver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiagonalConstraint is the X-Sudoku rule that neither main diagonal holds a
+// value twice.
+type DiagonalConstraint struct{}
+
+// Forbidden returns the values on the diagonals through cell i.
+func (c DiagonalConstraint) Forbidden(b *Board, i int) uint32 {
+	return forbiddenInGroups(b, c.groups(b), i)
+}
+
+// Check adds a Conflict to e for every pair of cells on a diagonal holding
+// the same value. Diagonal 0 runs down from the top left corner and diagonal
+// 1 down from the top right.
+func (c DiagonalConstraint) Check(b *Board, e *ValidationError) {
+	checkGroups(b, "diagonal", c.groups(b), e)
+}
+
+func (DiagonalConstraint) groups(b *Board) [][]int {
+	size := b.Size()
+	diagonals := [][]int{make([]int, size), make([]int, size)}
+	for r := 0; r < size; r++ {
+		diagonals[0][r] = r*size + r
+		diagonals[1][r] = r*size + size - 1 - r
+	}
+	return diagonals
+}
+
+// WindokuConstraint is the Windoku, or Hyper Sudoku, rule that the extra
+// windows between the boxes don't hold a value twice. On a 9x9 board these
+// are the four 3x3 windows with their top left corners at r2c2, r2c6, r6c2
+// and r6c6. Other boards have windows the shape of their boxes, one cell in
+// from each box.
+type WindokuConstraint struct{}
+
+// Forbidden returns the values in the window holding cell i, if there is one.
+func (c WindokuConstraint) Forbidden(b *Board, i int) uint32 {
+	return forbiddenInGroups(b, c.groups(b), i)
+}
+
+// Check adds a Conflict to e for every pair of cells in a window holding the
+// same value. Windows are numbered row by row.
+func (c WindokuConstraint) Check(b *Board, e *ValidationError) {
+	checkGroups(b, "window", c.groups(b), e)
+}
+
+func (WindokuConstraint) groups(b *Board) [][]int {
+	size := b.Size()
+	var windows [][]int
+	for top := 1; top+b.BoxRows < size; top += b.BoxRows + 1 {
+		for left := 1; left+b.BoxColumns < size; left += b.BoxColumns + 1 {
+			var window []int
+			for r := top; r < top+b.BoxRows; r++ {
+				for c := left; c < left+b.BoxColumns; c++ {
+					window = append(window, r*size+c)
+				}
+			}
+			windows = append(windows, window)
+		}
+	}
+	return windows
+}
+
+// forbiddenInGroups returns the values in the groups holding cell i.
+func forbiddenInGroups(b *Board, groups [][]int, i int) uint32 {
+	var forbidden uint32
+	for _, cells := range groups {
+		for _, cell := range cells {
+			if cell == i {
+				forbidden |= valuesIn(b, cells)
+				break
+			}
+		}
+	}
+	return forbidden
+}
+
+// variant is a built-in variant which can be picked by name.
+type variant struct {
+	name       string
+	aliases    []string
+	constraint Constraint
+}
+
+var variants = []variant{
+	{"x", []string{"diagonal"}, DiagonalConstraint{}},
+	{"windoku", []string{"hyper"}, WindokuConstraint{}},
+	{"antiknight", []string{"anti-knight", "knight"}, AntiKnightConstraint{}},
+	{"antiking", []string{"anti-king", "king"}, AntiKingConstraint{}},
+	{"nonconsecutive", []string{"non-consecutive"}, NonConsecutiveConstraint{}},
+}
+
+// ParseVariants returns the constraints of a comma separated list of
+// built-in variants, ignoring case - x (or diagonal) for X-Sudoku, windoku (or
+// hyper) for Windoku, antiknight, antiking and nonconsecutive. An empty list
+// returns no constraints.
+func ParseVariants(s string) ([]Constraint, error) {
+	var constraints []Constraint
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, v := range variants {
+			if name == v.name || containsString(v.aliases, name) {
+				constraints = append(constraints, v.constraint)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown variant %s", name)
+		}
+	}
+	return constraints, nil
+}
+
+// VariantName returns the name of a built-in variant's constraint, or an
+// empty string for any other constraint.
+func VariantName(c Constraint) string {
+	for _, v := range variants {
+		if v.constraint == c {
+			return v.name
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
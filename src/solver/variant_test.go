@@ -0,0 +1,110 @@
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVariantSolve(t *testing.T) {
+	tests := []struct {
+		variants, puzzle, solution string
+	}{
+		{"x", "000450000000000000000007203000300060008000000024000800000000000000940100090730000", "213456789789123456456897213971384562568219347324675891147562938632948175895731624"},
+		{"windoku", "000561000000000000000047306000700093002000000005000000000000060007050000080100000", "234561789761398245859247316148725693372916458695834172913482567427659831586173924"},
+		{"antiknight", "000400080060000000007000000009000000000200000200000003000000090600002004000000010", "123467589968125437457938126519743268836259741274816953745381692681592374392674815"},
+		{"antiking", "100306080000000000006000000008000000000900400694000003000000070400500031030010020", "125346789789125346346789152518463297273951468694278513851632974462597831937814625"},
+		{"nonconsecutive", "000000000000000002000000030000000000509604000000000000001000000600000000000000000", "135279468468513792792846135246381579579624813813957246351792684684135927927468351"},
+	}
+	for _, test := range tests {
+		b, _ := ParseBoard(test.puzzle, 0, 0)
+		if count, err := b.CountSolutions(context.Background(), 2); err != nil || count != 2 {
+			t.Errorf("%s: expected more than one solution without the variant - got %d, %v", test.variants, count, err)
+		}
+		var err error
+		if b.Constraints, err = ParseVariants(test.variants); err != nil {
+			t.Fatalf("%s: unexpected error %v", test.variants, err)
+		}
+		if count, err := b.CountSolutions(context.Background(), 0); err != nil || count != 1 {
+			t.Errorf("%s: expected a unique solution - got %d, %v", test.variants, count, err)
+		}
+		if err := b.Solve(context.Background(), nil); err != nil {
+			t.Fatalf("%s: unexpected error %v", test.variants, err)
+		}
+		if b.String() != test.solution {
+			t.Errorf("%s: expected %s - got %s instead", test.variants, test.solution, b)
+		}
+	}
+}
+
+func TestVariantValidate(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	b.Constraints, _ = ParseVariants("x,windoku")
+	// r2c2 and r9c9 are on the main diagonal, r4c4 and r3c3 in different
+	// windows
+	b.Cells[10], b.Cells[80] = 5, 5
+	b.Cells[30] = 7
+	b.Cells[20] = 7
+	err := b.Validate()
+	if err == nil {
+		t.Fatal("expected the board to be invalid")
+	}
+	if !strings.Contains(err.Error(), "5 appears twice in diagonal 1, at r2c2 and r9c9") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if !strings.Contains(err.Error(), "7 appears twice in window 1, at r3c3 and r4c4") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestWindokuWindows(t *testing.T) {
+	b, _ := NewBoard(3, 3)
+	windows := WindokuConstraint{}.groups(b)
+	if len(windows) != 4 {
+		t.Fatalf("expected 4 windows - got %d", len(windows))
+	}
+	expected := [][]int{
+		{10, 11, 12, 19, 20, 21, 28, 29, 30},
+		{14, 15, 16, 23, 24, 25, 32, 33, 34},
+		{46, 47, 48, 55, 56, 57, 64, 65, 66},
+		{50, 51, 52, 59, 60, 61, 68, 69, 70},
+	}
+	if !reflect.DeepEqual(windows, expected) {
+		t.Errorf("expected windows %v - got %v instead", expected, windows)
+	}
+
+	b, _ = NewBoard(2, 2)
+	if windows := (WindokuConstraint{}).groups(b); !reflect.DeepEqual(windows, [][]int{{5, 6, 9, 10}}) {
+		t.Errorf("unexpected windows on a 4x4 board %v", windows)
+	}
+}
+
+func TestParseVariants(t *testing.T) {
+	constraints, err := ParseVariants(" Diagonal, hyper")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(constraints, []Constraint{DiagonalConstraint{}, WindokuConstraint{}}) {
+		t.Errorf("unexpected constraints %v", constraints)
+	}
+	if name := VariantName(constraints[1]); name != "windoku" {
+		t.Errorf("expected windoku - got %s", name)
+	}
+	if constraints, err := ParseVariants(""); err != nil || len(constraints) != 0 {
+		t.Errorf("expected no constraints - got %v, %v", constraints, err)
+	}
+	if _, err := ParseVariants("x,sideways"); err == nil {
+		t.Error("expected an error for an unknown variant")
+	}
+
+	var b Board
+	if err := json.Unmarshal([]byte(`{"variants":["x","windoku"]}`), &b); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	data, _ := json.Marshal(&b)
+	if !strings.Contains(string(data), `"variants":["x","windoku"]`) {
+		t.Errorf("expected the variants in %s", data)
+	}
+}